@@ -1,85 +1,284 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
+	"riverboat/config"
+	"riverboat/http/calc"
+	"riverboat/http/hub"
+	"riverboat/http/idempotency"
+	"riverboat/http/limit"
+	"riverboat/http/logging"
+	"riverboat/http/metrics"
 	"riverboat/http/route"
+	"riverboat/http/tracing"
 	"riverboat/model"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	"goyave.dev/goyave/v4"
+	goyaveconfig "goyave.dev/goyave/v4/config"
 	"goyave.dev/goyave/v4/cors"
 )
 
+// scraped by Prometheus, never rate limited
+const metricsRoute = "/metrics"
+
+// the clock reachable backs off by
+var now, sleep = time.Now, time.Sleep
+
+// riverboat [command] [flags], serving the API when no command is given
 func main() {
-	fmt.Println("Goyave server active")
 	godotenv.Load(".env")
 
-	var uri, user, pw string
+	name, args := "serve", []string{}
+	if len(os.Args) > 1 {
+		name, args = os.Args[1], os.Args[2:]
+	}
 
-	if os.Getenv("APP_ENV") == "production" {
-		fmt.Printf("Production environment: production \n")
-		uri = "AURA_URI"
-		user = "AURA_USERNAME"
-		pw = "AURA_PASSWORD"
-	} else {
-		fmt.Printf("Production environment: development \n")
-		uri = "DB_URI"
-		user = "DB_USERNAME"
-		pw = "DB_PASSWORD"
+	command, ok := commands[name]
+	if !ok {
+		usage()
+		os.Exit(2)
 	}
 
-	dbUri, found := os.LookupEnv(uri)
-	//fmt.Println(dbUri)
-	if !found {
-		panic("DB_URI not set")
+	conf, err := config.Load()
+	// config dumps whatever it got and reports problems itself, tournament
+	// runs offline and needs no database settings
+	if err == nil && name != "config" && name != "tournament" {
+		err = conf.Validate()
+		if err == nil {
+			err = logging.Setup(logOutput(name), conf.Logging.Level, conf.Logging.Format)
+		}
 	}
-	dbUser, found := os.LookupEnv(user)
-	if !found {
-		panic("DB_USERNAME not set")
+	if err == nil {
+		err = command.run(conf, args)
 	}
-	dbPass, found := os.LookupEnv(pw)
-	if !found {
-		panic("DB_PASSWORD not set")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "riverboat "+name+":", err)
+		os.Exit(1)
 	}
+}
+
+// connect to Neo4j using the profile's credentials and pool settings
+func connect(conf config.Config) (route.Env, error) {
+	slog.Info("connecting to neo4j", "profile", conf.Profile)
 
-	neoDriver := route.Env{
-		Driver: driver(dbUri, dbUser, dbPass), // driver is thread-safe
+	neoDriver, err := driver(conf.Neo4j)
+	if err != nil {
+		return route.Env{}, err
 	}
 
+	return route.Env{Driver: neoDriver}, nil // driver is thread-safe
+}
+
+func serve(conf config.Config, args []string) error {
+	neoDriver, err := connect(conf)
+	if err != nil {
+		return err
+	}
+
+	server, _ := json.Marshal(map[string]interface{}{
+		"app": map[string]interface{}{"environment": conf.Profile},
+		"server": map[string]interface{}{
+			"host":    conf.Server.Host,
+			"port":    conf.Server.Port,
+			"timeout": int(conf.Server.Timeout.Seconds()),
+		},
+	})
+	if err := goyaveconfig.LoadJSON(string(server)); err != nil {
+		neoDriver.Driver.Close()
+		return err
+	}
+
+	// don't take traffic until Neo4j answers
+	if err := reachable(neoDriver, conf.Neo4j.StartupTimeout.Duration); err != nil {
+		neoDriver.Driver.Close()
+		return err
+	}
+	slog.Info("goyave server active", "host", conf.Server.Host, "port", conf.Server.Port)
+
+	stopTracing, err := tracing.Setup(conf.Tracing.Exporter, conf.Tracing.SampleRatio)
+	if err != nil {
+		neoDriver.Driver.Close()
+		return err
+	}
+
+	workers := route.NewWorkers()
+
 	handler := &route.Handler{
-		DB: &neoDriver,
+		DB:    &neoDriver,
+		Hub:   hub.New(256), // recent events per topic kept for Last-Event-ID replay
+		Ready: route.NewReadiness(&neoDriver, workers),
+	}
+
+	// webhook deliveries are queued from hub events and sent in the background
+	if conf.Features.Webhooks {
+		dispatcher := route.NewDispatcher(&neoDriver, workers)
+		handler.Hub.Listen(dispatcher.Enqueue)
+		dispatcher.Start()
+	}
+
+	// bots in a circle submit as soon as a space opens there
+	if conf.Features.Bots {
+		bots := route.Bots{DB: &neoDriver, Hub: handler.Hub, Workers: workers}
+		handler.Hub.Listen(bots.Open)
+	}
+
+	// optionally recompute payouts once a space's models settle down
+	if conf.Features.AutoRecalc {
+		recalculator := route.NewRecalculator(&neoDriver, handler.Hub, conf.Features.RecalcDelay.Duration)
+		recalculator.Workers = workers
+		handler.Hub.Listen(recalculator.Touch)
 	}
 
-	// start registration route
-	if err := goyave.Start(func(router *goyave.Router) {
-		router.CORS(cors.Default())
+	// on SIGTERM end the event streams so goyave's graceful stop only has
+	// ordinary requests left to drain
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		<-signals
+		slog.Info("shutting down")
+		handler.Hub.Close()
+		goyave.Stop()
+	}()
+
+	calc.Observe = metrics.Payouts
+	metrics.Business(func() (model.Totals, error) { return route.Totals(&neoDriver) })
+
+	quotas := make(map[string]limit.Quota)
+	for route, quota := range conf.RateLimits {
+		if route == metricsRoute {
+			continue // a scraper backing off would leave gaps in every series
+		}
+		quotas[route] = limit.Quota{Requests: quota.Requests, Per: quota.Per.Duration}
+	}
+	limiter := limit.Limiter{
+		Store:    limit.NewMemory(),
+		Quotas:   quotas,
+		Proxies:  conf.Server.Proxies,
+		Rejected: metrics.Limited,
+	}
+
+	keys := idempotency.Keys{
+		Store:   idempotency.NewMemory(),
+		TTL:     conf.Server.IdempotencyTTL.Duration,
+		Proxies: conf.Server.Proxies,
+	}
+
+	origins := cors.Default()
+	origins.AllowedOrigins = conf.Server.CORSOrigins
+	origins.AllowedHeaders = append(origins.AllowedHeaders,
+		idempotency.Header, logging.Header, "traceparent", "tracestate", "If-Match")
+	origins.ExposedHeaders = append(origins.ExposedHeaders,
+		idempotency.ReplayHeader, logging.Header, "Retry-After", "ETag")
+
+	// start registration route, blocks until the server has stopped
+	err = goyave.Start(func(router *goyave.Router) {
+		router.CORS(origins)
+		router.GlobalMiddleware(tracing.Middleware, logging.Middleware, metrics.Middleware, limiter.Middleware, keys.Middleware)
 		router.Get("/", handler.GetStatus)
-		router.Get("/circles", handler.ListCircles)       // public circles
-		router.Get("/spaces/{cuuid}", handler.ListSpaces) // spawned by circle
-		router.Get("/joined/{cuuid}", handler.ListJoined)
-		router.Get("/models/{suuid}", handler.ListModels)
+		router.Get("/healthz", handler.Healthz) // process alive
+		router.Get("/readyz", handler.Readyz)   // dependencies too, 503 when not
+
+		// a GET, so idempotency keys never apply to it
+		router.Get(metricsRoute, metrics.Handler)
+
+		// lists take cursor, limit, sort and filters, see the ListProps
+		router.Get("/circles", handler.ListCircles).Validate(model.CircleListProps)      // public circles
+		router.Get("/spaces/{cuuid}", handler.ListSpaces).Validate(model.SpaceListProps) // spawned by circle
+		router.Get("/joined/{cuuid}", handler.ListJoined).Validate(model.PlayerListProps)
+		router.Get("/models/{suuid}", handler.ListModels).Validate(model.NameListProps)
 		router.Get("/space/{suuid}", handler.GetSpace)
-		router.Get("/payouts/{suuid}", handler.ListPayouts)
+		router.Get("/space/{suuid}/consensus", handler.Consensus).Validate(model.ConsensusProps)
+		router.Get("/payouts/{suuid}", handler.ListPayouts).Validate(model.NameListProps)
+		router.Get("/payouts/{suuid}/explain", handler.ExplainPayouts)
+		router.Get("/payouts/{suuid}/as_of", handler.PayoutsAsOf).Validate(model.AsOfProps)
+		router.Get("/revisions/{suuid}/{puuid}", handler.ListRevisions)
+		router.Get("/events/circle/{cuuid}", handler.StreamCircle)
+		router.Get("/events/space/{suuid}", handler.StreamSpace)
+		router.Get("/circles/{cuuid}/leaderboard", handler.Leaderboard).Validate(model.WindowProps)
+		router.Get("/webhooks/{cuuid}", handler.ListWebhooks)
+		router.Get("/deliveries/{wuuid}", handler.ListDeliveries)
 		router.Post("/greeting", handler.Greeting)
 		router.Post("/join", handler.Join).Validate(model.PlayerCircleProps)
 		router.Post("/leave", handler.Leave).Validate(model.PlayerCircleProps)
-		router.Post("/add_random", handler.AddRandom).Validate(model.CircleProps)
+		router.Post("/add_random", handler.AddRandom).Validate(model.RandomProps)
 		router.Post("/submit", handler.SubmitModel).Validate(model.SubmissionProps)
 		router.Post("/delete_model", handler.DeleteModel).Validate(model.PlayerSpaceProps)
 		router.Post("/calc", handler.CalculatePayouts).Validate(model.SpaceProps)
+		router.Post("/space/{suuid}/simulate", handler.Simulate).Validate(model.SimulationProps)
+		router.Post("/space", handler.CreateSpace).Validate(model.NewSpaceProps)
+		router.Post("/resolve", handler.Resolve).Validate(model.ResolveProps)
+		router.Post("/webhook", handler.CreateWebhook).Validate(model.WebhookProps)
+		router.Post("/delete_webhook", handler.DeleteWebhook).Validate(model.UuidProps)
+
+	})
 
-	}); err != nil {
+	// requests are drained, now the background work and the driver
+	handler.Hub.Close()
+	if err := workers.Drain(conf.Server.ShutdownTimeout.Duration); err != nil {
+		slog.Error("draining workers", "err", err)
+	}
+	if err := neoDriver.Driver.Close(); err != nil {
+		slog.Error("closing neo4j driver", "err", err)
+	}
+	if err := stopTracing(context.Background()); err != nil {
+		slog.Error("flushing traces", "err", err)
+	}
+
+	if err != nil {
 		os.Exit(err.(*goyave.Error).ExitCode)
 	}
+	return nil
 }
 
-func driver(uri string, user string, pw string) neo4j.Driver {
-	token := neo4j.BasicAuth(user, pw, "")
-	result, err := neo4j.NewDriver(uri, token)
-	if err != nil {
-		panic(err)
+// verify connectivity, backing off from half a second up to 8 seconds
+// between attempts, until timeout
+func reachable(db route.Env, timeout time.Duration) error {
+	deadline := now().Add(timeout)
+	wait := 500 * time.Millisecond
+
+	for {
+		err := db.Driver.VerifyConnectivity()
+		if err == nil {
+			return nil
+		}
+		if now().Add(wait).After(deadline) {
+			return fmt.Errorf("neo4j unreachable after %s: %w", timeout, err)
+		}
+
+		slog.Warn("waiting for neo4j", "retry_in", wait.String(), "err", err)
+		sleep(wait)
+		if wait < 8*time.Second {
+			wait *= 2
+		}
+	}
+}
+
+// the server logs to stdout for Elastic Beanstalk, the CLI commands to
+// stderr so their output can be piped
+func logOutput(command string) io.Writer {
+	if command == "serve" {
+		return os.Stdout
 	}
-	return result
+	return os.Stderr
+}
+
+func driver(conf config.Neo4j) (neo4j.Driver, error) {
+	token := neo4j.BasicAuth(conf.Username, conf.Password, "")
+	return neo4j.NewDriver(conf.URI, token, func(c *neo4j.Config) {
+		c.MaxConnectionPoolSize = conf.MaxPoolSize
+		c.ConnectionAcquisitionTimeout = conf.AcquireTimeout.Duration
+		c.SocketConnectTimeout = conf.ConnectTimeout.Duration
+		c.MaxConnectionLifetime = conf.MaxConnectionLifetime.Duration
+		c.MaxTransactionRetryTime = conf.MaxRetryTime.Duration
+	})
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"riverboat/http/route"
+	"testing"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// driver that is unreachable for its first failures attempts
+type flaky struct {
+	neo4j.Driver
+	failures int
+	calls    int
+}
+
+func (f *flaky) VerifyConnectivity() error {
+	f.calls++
+	if f.calls <= f.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestReachable(t *testing.T) {
+	defer func(n func() time.Time, s func(time.Duration)) { now, sleep = n, s }(now, sleep)
+
+	second := time.Second
+	cases := []struct {
+		failures int
+		timeout  time.Duration
+		waits    []time.Duration
+		ok       bool
+	}{
+		{0, 0, nil, true},
+		{3, time.Minute, []time.Duration{second / 2, second, 2 * second}, true},
+		{100, 0, nil, false},
+		// the next wait of 8s would end after the deadline
+		{100, 10 * second, []time.Duration{second / 2, second, 2 * second, 4 * second}, false},
+		// waits stop doubling at 8s
+		{100, time.Minute, []time.Duration{
+			second / 2, second, 2 * second, 4 * second, 8 * second, 8 * second, 8 * second, 8 * second, 8 * second, 8 * second,
+		}, false},
+	}
+	for _, c := range cases {
+		clock := time.Unix(0, 0)
+		var waits []time.Duration
+		now = func() time.Time { return clock }
+		sleep = func(wait time.Duration) {
+			waits = append(waits, wait)
+			clock = clock.Add(wait)
+		}
+
+		db := &flaky{failures: c.failures}
+		err := reachable(route.Env{Driver: db}, c.timeout)
+		if (err == nil) != c.ok {
+			t.Errorf("%d failures within %s: %v", c.failures, c.timeout, err)
+		}
+		if !reflect.DeepEqual(waits, c.waits) {
+			t.Errorf("%d failures within %s: waited %v, want %v", c.failures, c.timeout, waits, c.waits)
+		}
+		if db.calls != len(c.waits)+1 {
+			t.Errorf("%d failures within %s: tried %d times", c.failures, c.timeout, db.calls)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"riverboat/config"
+	"riverboat/http/calc"
+	"riverboat/http/route"
+	"riverboat/model"
+	"riverboat/sim"
+	"sort"
+	"time"
+)
+
+type command struct {
+	summary string
+	run     func(conf config.Config, args []string) error
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"serve":        {"start the HTTP API (default)", serve},
+		"config":       {"print the loaded configuration with secrets redacted", dump},
+		"migrate":      {"apply pending schema migrations", migrate},
+		"seed":         {"create a demo circle with players and a space", seed},
+		"recalc":       {"recompute payouts for --space", recalc},
+		"resolve":      {"resolve --space to --field, --weights or --void", resolve},
+		"export":       {"write every node and relationship as JSON", export},
+		"import":       {"load an export into an empty database", load},
+		"check-ledger": {"verify escrow and payout invariants", checkLedger},
+		"tournament":   {"simulate every scoring rule on synthetic spaces, offline", tournament},
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: riverboat [command] [flags]")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-13s %s\n", name, commands[name].summary)
+	}
+}
+
+func dump(conf config.Config, args []string) error {
+	flag.NewFlagSet("config", flag.ExitOnError).Parse(args)
+
+	if err := printJSON(os.Stdout, conf.Redacted()); err != nil {
+		return err
+	}
+	return conf.Validate()
+}
+
+func migrate(conf config.Config, args []string) error {
+	flag.NewFlagSet("migrate", flag.ExitOnError).Parse(args)
+
+	db, err := connect(conf)
+	if err != nil {
+		return err
+	}
+	defer db.Driver.Close()
+
+	version, err := route.Migrate(&db)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Schema at version", version)
+	return nil
+}
+
+func seed(conf config.Config, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	players := flags.Int("players", 5, "players to create")
+	flags.Parse(args)
+
+	if *players < 1 {
+		return errors.New("--players must be at least 1")
+	}
+
+	db, err := connect(conf)
+	if err != nil {
+		return err
+	}
+	defer db.Driver.Close()
+
+	cuuid, err := route.Seed(&db, *players)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Seeded circle", cuuid)
+	return nil
+}
+
+func recalc(conf config.Config, args []string) error {
+	flags := flag.NewFlagSet("recalc", flag.ExitOnError)
+	suuid := flags.String("space", "", "space uuid")
+	flags.Parse(args)
+
+	if *suuid == "" {
+		return errors.New("--space is required")
+	}
+
+	db, err := connect(conf)
+	if err != nil {
+		return err
+	}
+	defer db.Driver.Close()
+
+	payouts, err := route.Recalc(&db, *suuid)
+	if err != nil {
+		return err
+	}
+	return printJSON(os.Stdout, payouts)
+}
+
+func resolve(conf config.Config, args []string) error {
+	flags := flag.NewFlagSet("resolve", flag.ExitOnError)
+	suuid := flags.String("space", "", "space uuid")
+	field := flags.String("field", "", "field that came true")
+	mix := flags.String("weights", "", `JSON share per field, e.g. '{"yes": 0.5, "no": 0.5}'`)
+	void := flags.Bool("void", false, "void the space and refund every stake")
+	flags.Parse(args)
+
+	if *suuid == "" {
+		return errors.New("--space is required")
+	}
+
+	var weights map[string]float64
+	switch {
+	case *field != "" && *mix == "" && !*void:
+		weights = map[string]float64{*field: 1}
+	case *mix != "" && *field == "" && !*void:
+		if err := json.Unmarshal([]byte(*mix), &weights); err != nil {
+			return fmt.Errorf("--weights: %w", err)
+		}
+	case *void && *field == "" && *mix == "":
+		// refund everyone, no weights to settle on
+	default:
+		return errors.New("resolve with one of --field, --weights or --void")
+	}
+
+	db, err := connect(conf)
+	if err != nil {
+		return err
+	}
+	defer db.Driver.Close()
+
+	res, err := route.ResolveSpace(&db, *suuid, weights)
+	if err != nil {
+		return err
+	}
+	fmt.Println(res)
+	return nil
+}
+
+func export(conf config.Config, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	out := flags.String("out", "", "file to write, stdout when empty")
+	flags.Parse(args)
+
+	db, err := connect(conf)
+	if err != nil {
+		return err
+	}
+	defer db.Driver.Close()
+
+	dump, err := route.Export(&db)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return printJSON(os.Stdout, dump)
+	}
+	file, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return printJSON(file, dump)
+}
+
+func load(conf config.Config, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	in := flags.String("in", "", "file to read, stdin when empty")
+	flags.Parse(args)
+
+	var source io.Reader = os.Stdin
+	if *in != "" {
+		file, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		source = file
+	}
+
+	var dump model.Dump
+	if err := json.NewDecoder(source).Decode(&dump); err != nil {
+		return err
+	}
+
+	db, err := connect(conf)
+	if err != nil {
+		return err
+	}
+	defer db.Driver.Close()
+
+	res, err := route.Import(&db, dump)
+	if err != nil {
+		return err
+	}
+	fmt.Println(res)
+	return nil
+}
+
+func checkLedger(conf config.Config, args []string) error {
+	flag.NewFlagSet("check-ledger", flag.ExitOnError).Parse(args)
+
+	db, err := connect(conf)
+	if err != nil {
+		return err
+	}
+	defer db.Driver.Close()
+
+	problems, err := route.CheckLedger(&db)
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d ledger problems", len(problems))
+	}
+	fmt.Println("Ledger balances.")
+	return nil
+}
+
+// offline Monte Carlo tournament over every calc scoring rule
+func tournament(conf config.Config, args []string) error {
+	flags := flag.NewFlagSet("tournament", flag.ExitOnError)
+	cfg := sim.Config{}
+	flags.IntVar(&cfg.Spaces, "spaces", 1000, "number of synthetic spaces")
+	flags.IntVar(&cfg.Players, "players", 12, "players in the population")
+	flags.IntVar(&cfg.Fields, "fields", 4, "fields per space")
+	flags.Float64Var(&cfg.Stake, "stake", 100, "stake per player per space")
+	flags.Int64Var(&cfg.Seed, "seed", time.Now().UnixNano(), "random seed")
+	rule := flags.String("rule", "", "only run this scoring rule")
+	format := flags.String("format", "csv", "output format: csv or json")
+	flags.Parse(args)
+
+	if cfg.Spaces < 1 || cfg.Players < 2 || cfg.Fields < 2 {
+		return errors.New("need at least 1 space, 2 players and 2 fields")
+	}
+
+	rules := calc.Rules
+	if *rule != "" {
+		chosen, ok := calc.Rules[*rule]
+		if !ok {
+			return errors.New("unknown rule: " + *rule)
+		}
+		rules = map[string]calc.Rule{*rule: chosen}
+	}
+
+	report, err := sim.Run(cfg, rules)
+	if err != nil {
+		return err
+	}
+	if *format == "json" {
+		return sim.WriteJSON(os.Stdout, report)
+	}
+	return sim.WriteCSV(os.Stdout, report)
+}
+
+func printJSON(w io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
@@ -0,0 +1,321 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds every setting riverboat reads at startup
+type Config struct {
+	Profile  string   `json:"profile"`
+	Neo4j    Neo4j    `json:"neo4j"`
+	Server   Server   `json:"server"`
+	Features Features `json:"features"`
+	Tracing  Tracing  `json:"tracing"`
+	Logging  Logging  `json:"logging"`
+
+	// quotas by route template, routes not listed are unlimited
+	RateLimits map[string]Quota `json:"rate_limits"`
+}
+
+type Neo4j struct {
+	URI                   string   `json:"uri"`
+	Username              string   `json:"username"`
+	Password              string   `json:"password"`
+	MaxPoolSize           int      `json:"max_pool_size"`
+	AcquireTimeout        Duration `json:"acquire_timeout"`
+	ConnectTimeout        Duration `json:"connect_timeout"`
+	MaxConnectionLifetime Duration `json:"max_connection_lifetime"`
+	MaxRetryTime          Duration `json:"max_retry_time"`
+	StartupTimeout        Duration `json:"startup_timeout"`
+}
+
+type Server struct {
+	Host            string   `json:"host"`
+	Port            int      `json:"port"`
+	Timeout         Duration `json:"timeout"`
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+	CORSOrigins     []string `json:"cors_origins"`
+	Proxies         int      `json:"proxies"` // trusted hops appending to X-Forwarded-For
+	IdempotencyTTL  Duration `json:"idempotency_ttl"`
+}
+
+type Features struct {
+	AutoRecalc  bool     `json:"auto_recalc"`
+	RecalcDelay Duration `json:"recalc_delay"`
+	Bots        bool     `json:"bots"`
+	Webhooks    bool     `json:"webhooks"`
+}
+
+type Tracing struct {
+	Exporter    string  `json:"exporter"` // none or stdout
+	SampleRatio float64 `json:"sample_ratio"`
+}
+
+type Logging struct {
+	Level  string `json:"level"`  // debug, info, warn or error
+	Format string `json:"format"` // json or text
+}
+
+// Requests per Per, in bursts of up to Requests
+type Quota struct {
+	Requests int      `json:"requests"`
+	Per      Duration `json:"per"`
+}
+
+// Duration reads and writes as "2s", "500ms" and so on
+type Duration struct{ time.Duration }
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// Load layers profile defaults, the optional CONFIG_FILE and then env vars,
+// the last of which .env may already have populated
+func Load() (Config, error) {
+	name := os.Getenv("APP_ENV")
+	if name == "" {
+		name = "development"
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		return Config{}, fmt.Errorf("APP_ENV: unknown profile %q", name)
+	}
+	conf := profile.defaults
+	conf.Profile = name
+	conf.Server.CORSOrigins = append([]string(nil), conf.Server.CORSOrigins...) // don't decode into the shared defaults
+	conf.RateLimits = make(map[string]Quota)
+	for route, quota := range profile.defaults.RateLimits {
+		conf.RateLimits[route] = quota
+	}
+
+	path, set := os.LookupEnv("CONFIG_FILE")
+	if !set {
+		path = "riverboat.json"
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &conf); err != nil {
+			return Config{}, fmt.Errorf("%s: %w", path, err)
+		}
+		conf.Profile = name // the file can't switch profiles
+	} else if set {
+		return Config{}, err // only a missing default file is fine
+	}
+
+	var errs []string
+	env := func(key string, parse func(string) error) {
+		if value, found := os.LookupEnv(key); found {
+			if err := parse(value); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", key, err))
+			}
+		}
+	}
+
+	prefix := profile.credentials
+	env(prefix+"_URI", setString(&conf.Neo4j.URI))
+	env(prefix+"_USERNAME", setString(&conf.Neo4j.Username))
+	env(prefix+"_PASSWORD", setString(&conf.Neo4j.Password))
+	env("NEO4J_MAX_POOL_SIZE", setInt(&conf.Neo4j.MaxPoolSize))
+	env("NEO4J_ACQUIRE_TIMEOUT", setDuration(&conf.Neo4j.AcquireTimeout))
+	env("NEO4J_CONNECT_TIMEOUT", setDuration(&conf.Neo4j.ConnectTimeout))
+	env("NEO4J_MAX_CONNECTION_LIFETIME", setDuration(&conf.Neo4j.MaxConnectionLifetime))
+	env("NEO4J_MAX_RETRY_TIME", setDuration(&conf.Neo4j.MaxRetryTime))
+	env("NEO4J_STARTUP_TIMEOUT", setDuration(&conf.Neo4j.StartupTimeout))
+	env("HOST", setString(&conf.Server.Host))
+	env("PORT", setInt(&conf.Server.Port)) // set by Elastic Beanstalk
+	env("SERVER_TIMEOUT", setDuration(&conf.Server.Timeout))
+	env("SHUTDOWN_TIMEOUT", setDuration(&conf.Server.ShutdownTimeout))
+	env("CORS_ORIGINS", setList(&conf.Server.CORSOrigins))
+	env("TRUSTED_PROXIES", setInt(&conf.Server.Proxies))
+	env("RATE_LIMITS", setQuotas(conf.RateLimits))
+	env("IDEMPOTENCY_TTL", setDuration(&conf.Server.IdempotencyTTL))
+	env("AUTO_RECALC", setBool(&conf.Features.AutoRecalc))
+	env("RECALC_DELAY", setDuration(&conf.Features.RecalcDelay))
+	env("BOTS", setBool(&conf.Features.Bots))
+	env("WEBHOOKS", setBool(&conf.Features.Webhooks))
+	env("TRACE_EXPORTER", setString(&conf.Tracing.Exporter))
+	env("TRACE_SAMPLE_RATIO", setFloat(&conf.Tracing.SampleRatio))
+	env("LOG_LEVEL", setString(&conf.Logging.Level))
+	env("LOG_FORMAT", setString(&conf.Logging.Format))
+
+	if len(errs) > 0 {
+		return Config{}, errors.New(strings.Join(errs, "; "))
+	}
+	return conf, nil
+}
+
+// Validate reports every missing or out-of-range setting at once, naming
+// the env var that would fix it
+func (c Config) Validate() error {
+	var errs []string
+	prefix := profiles[c.Profile].credentials
+
+	if c.Neo4j.URI == "" {
+		errs = append(errs, prefix+"_URI not set")
+	}
+	if c.Neo4j.Username == "" {
+		errs = append(errs, prefix+"_USERNAME not set")
+	}
+	if c.Neo4j.Password == "" {
+		errs = append(errs, prefix+"_PASSWORD not set")
+	}
+	if c.Neo4j.MaxPoolSize < 1 {
+		errs = append(errs, "NEO4J_MAX_POOL_SIZE must be at least 1")
+	}
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		errs = append(errs, "PORT must be between 1 and 65535")
+	}
+	if c.Server.Timeout.Duration < time.Second {
+		errs = append(errs, "SERVER_TIMEOUT must be at least 1s")
+	}
+	if c.Neo4j.StartupTimeout.Duration <= 0 {
+		errs = append(errs, "NEO4J_STARTUP_TIMEOUT must be positive")
+	}
+	if c.Server.ShutdownTimeout.Duration <= 0 {
+		errs = append(errs, "SHUTDOWN_TIMEOUT must be positive")
+	}
+	if len(c.Server.CORSOrigins) == 0 {
+		errs = append(errs, "CORS_ORIGINS must list at least one origin")
+	}
+	if c.Features.AutoRecalc && c.Features.RecalcDelay.Duration <= 0 {
+		errs = append(errs, "RECALC_DELAY must be positive when AUTO_RECALC is on")
+	}
+	if c.Server.IdempotencyTTL.Duration <= 0 {
+		errs = append(errs, "IDEMPOTENCY_TTL must be positive")
+	}
+	if c.Server.Proxies < 0 {
+		errs = append(errs, "TRUSTED_PROXIES can't be negative")
+	}
+	for route, quota := range c.RateLimits {
+		if !strings.HasPrefix(route, "/") || quota.Requests < 1 || quota.Per.Duration <= 0 {
+			errs = append(errs, fmt.Sprintf("RATE_LIMITS: bad quota for %q", route))
+		}
+	}
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	default:
+		errs = append(errs, "TRACE_EXPORTER must be none or stdout")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, "TRACE_SAMPLE_RATIO must be between 0 and 1")
+	}
+	switch strings.ToLower(c.Logging.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, "LOG_LEVEL must be debug, info, warn or error")
+	}
+	if c.Logging.Format != "json" && c.Logging.Format != "text" {
+		errs = append(errs, "LOG_FORMAT must be json or text")
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Redacted is safe to print or log
+func (c Config) Redacted() Config {
+	if c.Neo4j.Password != "" {
+		c.Neo4j.Password = "[redacted]"
+	}
+	if at := strings.Index(c.Neo4j.URI, "@"); at >= 0 {
+		if scheme := strings.Index(c.Neo4j.URI, "://"); scheme >= 0 && scheme < at {
+			c.Neo4j.URI = c.Neo4j.URI[:scheme+3] + "[redacted]" + c.Neo4j.URI[at:]
+		}
+	}
+	return c
+}
+
+func setString(dst *string) func(string) error {
+	return func(value string) error {
+		*dst = value
+		return nil
+	}
+}
+
+func setInt(dst *int) func(string) error {
+	return func(value string) (err error) {
+		*dst, err = strconv.Atoi(value)
+		return err
+	}
+}
+
+func setFloat(dst *float64) func(string) error {
+	return func(value string) (err error) {
+		*dst, err = strconv.ParseFloat(value, 64)
+		return err
+	}
+}
+
+// "/submit=30/1m,/calc=6/1m" sets those routes and leaves the rest
+func setQuotas(dst map[string]Quota) func(string) error {
+	return func(value string) error {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			route, rate, found := strings.Cut(item, "=")
+			requests, per, found2 := strings.Cut(rate, "/")
+			if !found || !found2 {
+				return fmt.Errorf("%q is not route=requests/period", item)
+			}
+
+			var quota Quota
+			var err error
+			if quota.Requests, err = strconv.Atoi(requests); err != nil {
+				return err
+			}
+			if quota.Per.Duration, err = time.ParseDuration(per); err != nil {
+				return err
+			}
+			dst[route] = quota
+		}
+		return nil
+	}
+}
+
+func setBool(dst *bool) func(string) error {
+	return func(value string) (err error) {
+		*dst, err = strconv.ParseBool(value)
+		return err
+	}
+}
+
+func setDuration(dst *Duration) func(string) error {
+	return func(value string) (err error) {
+		dst.Duration, err = time.ParseDuration(value)
+		return err
+	}
+}
+
+func setList(dst *[]string) func(string) error {
+	return func(value string) error {
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*dst = list
+		return nil
+	}
+}
@@ -0,0 +1,322 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// every variable Load reads, cleared so the host environment can't leak in
+var keys = []string{
+	"APP_ENV", "CONFIG_FILE",
+	"DB_URI", "DB_USERNAME", "DB_PASSWORD", "AURA_URI", "AURA_USERNAME", "AURA_PASSWORD",
+	"NEO4J_MAX_POOL_SIZE", "NEO4J_ACQUIRE_TIMEOUT", "NEO4J_CONNECT_TIMEOUT",
+	"NEO4J_MAX_CONNECTION_LIFETIME", "NEO4J_MAX_RETRY_TIME", "NEO4J_STARTUP_TIMEOUT",
+	"HOST", "PORT", "SERVER_TIMEOUT", "SHUTDOWN_TIMEOUT", "CORS_ORIGINS", "TRUSTED_PROXIES",
+	"RATE_LIMITS", "IDEMPOTENCY_TTL", "AUTO_RECALC", "RECALC_DELAY", "BOTS", "WEBHOOKS",
+	"TRACE_EXPORTER", "TRACE_SAMPLE_RATIO", "LOG_LEVEL", "LOG_FORMAT",
+}
+
+// a clean environment with vars set, reading file (if any) as CONFIG_FILE
+func setenv(t *testing.T, file string, vars map[string]string) {
+	t.Helper()
+	for _, key := range keys {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+
+	path := filepath.Join(t.TempDir(), "riverboat.json")
+	if file != "" {
+		if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("CONFIG_FILE", path)
+	} else {
+		// no file at the default path either
+		wd, _ := os.Getwd()
+		os.Chdir(t.TempDir())
+		t.Cleanup(func() { os.Chdir(wd) })
+	}
+
+	for key, value := range vars {
+		t.Setenv(key, value)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	cases := []struct {
+		env     string
+		prefix  string
+		port    int
+		level   string
+		bots    bool
+		proxies int
+	}{
+		{"", "DB", 8080, "debug", true, 0}, // development by default
+		{"development", "DB", 8080, "debug", true, 0},
+		{"staging", "AURA", 5000, "debug", true, 2},
+		{"production", "AURA", 5000, "info", false, 2},
+	}
+
+	for _, c := range cases {
+		t.Run(c.env, func(t *testing.T) {
+			setenv(t, "", map[string]string{
+				"APP_ENV":              c.env,
+				c.prefix + "_URI":      "neo4j://db:7687",
+				c.prefix + "_USERNAME": "neo4j",
+				c.prefix + "_PASSWORD": "secret",
+			})
+			if c.env == "" {
+				os.Unsetenv("APP_ENV")
+			}
+
+			conf, err := Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := conf.Validate(); err != nil {
+				t.Errorf("defaults don't validate: %v", err)
+			}
+			if conf.Neo4j.URI != "neo4j://db:7687" || conf.Neo4j.Password != "secret" {
+				t.Errorf("credentials not read from %s_*", c.prefix)
+			}
+			if conf.Server.Port != c.port || conf.Logging.Level != c.level ||
+				conf.Features.Bots != c.bots || conf.Server.Proxies != c.proxies {
+				t.Errorf("got %+v", conf)
+			}
+		})
+	}
+}
+
+func TestUnknownProfile(t *testing.T) {
+	setenv(t, "", map[string]string{"APP_ENV": "prod"})
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "APP_ENV") {
+		t.Errorf("got %v, want an APP_ENV error", err)
+	}
+}
+
+func TestLayering(t *testing.T) {
+	// env beats the file, which beats the profile
+	setenv(t, `{"server": {"port": 9000, "timeout": "30s"}, "profile": "production"}`, map[string]string{
+		"SERVER_TIMEOUT": "45s",
+	})
+
+	conf, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Server.Port != 9000 {
+		t.Errorf("port %d, want 9000 from the file", conf.Server.Port)
+	}
+	if conf.Server.Timeout.Duration != 45*time.Second {
+		t.Errorf("timeout %s, want 45s from the env", conf.Server.Timeout)
+	}
+	if conf.Profile != "development" {
+		t.Errorf("profile %q, the file must not switch it", conf.Profile)
+	}
+	if conf.Server.Host != "127.0.0.1" {
+		t.Errorf("host %q, want the profile default", conf.Server.Host)
+	}
+}
+
+func TestFileErrors(t *testing.T) {
+	setenv(t, `{"server": {"timeout": "soon"}}`, nil)
+	if _, err := Load(); err == nil {
+		t.Error("loaded a file with a bad duration")
+	}
+
+	setenv(t, "", map[string]string{"CONFIG_FILE": filepath.Join(t.TempDir(), "missing.json")})
+	if _, err := Load(); err == nil {
+		t.Error("a missing CONFIG_FILE that was asked for is not an error")
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	setenv(t, "", map[string]string{
+		"NEO4J_MAX_POOL_SIZE": "7",
+		"CORS_ORIGINS":        " https://a.example , ,https://b.example",
+		"AUTO_RECALC":         "true",
+		"RECALC_DELAY":        "750ms",
+		"TRACE_SAMPLE_RATIO":  "0.25",
+	})
+
+	conf, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Neo4j.MaxPoolSize != 7 {
+		t.Errorf("pool size %d, want 7", conf.Neo4j.MaxPoolSize)
+	}
+	if strings.Join(conf.Server.CORSOrigins, " ") != "https://a.example https://b.example" {
+		t.Errorf("origins %q", conf.Server.CORSOrigins)
+	}
+	if !conf.Features.AutoRecalc || conf.Features.RecalcDelay.Duration != 750*time.Millisecond {
+		t.Errorf("recalc %v after %s", conf.Features.AutoRecalc, conf.Features.RecalcDelay)
+	}
+	if conf.Tracing.SampleRatio != 0.25 {
+		t.Errorf("sample ratio %g, want 0.25", conf.Tracing.SampleRatio)
+	}
+}
+
+func TestEnvParseErrors(t *testing.T) {
+	setenv(t, "", map[string]string{
+		"PORT":           "eighty",
+		"BOTS":           "maybe",
+		"SERVER_TIMEOUT": "10",
+	})
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("loaded unparseable env vars")
+	}
+	for _, key := range []string{"PORT", "BOTS", "SERVER_TIMEOUT"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("%q does not name %s", err, key)
+		}
+	}
+}
+
+func TestSetQuotas(t *testing.T) {
+	cases := []struct {
+		value string
+		want  map[string]Quota
+		ok    bool
+	}{
+		{"/submit=5/1s", map[string]Quota{"/submit": {5, Duration{time.Second}}}, true},
+		{" /a=1/1m , /b=2/2h ,", map[string]Quota{
+			"/a": {1, Duration{time.Minute}},
+			"/b": {2, Duration{2 * time.Hour}},
+		}, true},
+		{"", map[string]Quota{}, true},
+		{"/submit", nil, false},
+		{"/submit=5", nil, false},
+		{"/submit=five/1s", nil, false},
+		{"/submit=5/soon", nil, false},
+	}
+
+	for _, c := range cases {
+		quotas := make(map[string]Quota)
+		err := setQuotas(quotas)(c.value)
+		if (err == nil) != c.ok {
+			t.Errorf("%q: err = %v, want ok=%v", c.value, err, c.ok)
+			continue
+		}
+		if c.ok && !equalQuotas(quotas, c.want) {
+			t.Errorf("%q: got %v, want %v", c.value, quotas, c.want)
+		}
+	}
+}
+
+func TestRateLimitsKeepDefaults(t *testing.T) {
+	setenv(t, "", map[string]string{"RATE_LIMITS": "/submit=1/1s"})
+
+	conf, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.RateLimits["/submit"].Requests != 1 || conf.RateLimits["/calc"] != writeLimits["/calc"] {
+		t.Errorf("rate limits %v", conf.RateLimits)
+	}
+	if writeLimits["/submit"].Requests != 30 {
+		t.Error("overriding a quota changed the shared profile defaults")
+	}
+}
+
+func TestDuration(t *testing.T) {
+	var d Duration
+	for input, want := range map[string]time.Duration{
+		`"1m30s"`: 90 * time.Second,
+		`"250ms"`: 250 * time.Millisecond,
+		`"0s"`:    0,
+	} {
+		if err := json.Unmarshal([]byte(input), &d); err != nil || d.Duration != want {
+			t.Errorf("%s: got %s, %v, want %s", input, d, err, want)
+		}
+	}
+	for _, input := range []string{`"90"`, `90`, `"later"`} {
+		if err := json.Unmarshal([]byte(input), &d); err == nil {
+			t.Errorf("%s: parsed as %s", input, d)
+		}
+	}
+
+	out, _ := json.Marshal(Duration{2 * time.Second})
+	if string(out) != `"2s"` {
+		t.Errorf("marshalled as %s, want \"2s\"", out)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() Config {
+		conf := profiles["production"].defaults
+		conf.Profile = "production"
+		conf.Neo4j.URI, conf.Neo4j.Username, conf.Neo4j.Password = "neo4j+s://db", "neo4j", "secret"
+		return conf
+	}
+
+	cases := []struct {
+		name   string
+		change func(*Config)
+		want   string
+	}{
+		{"no uri", func(c *Config) { c.Neo4j.URI = "" }, "AURA_URI not set"},
+		{"no password", func(c *Config) { c.Neo4j.Password = "" }, "AURA_PASSWORD not set"},
+		{"empty pool", func(c *Config) { c.Neo4j.MaxPoolSize = 0 }, "NEO4J_MAX_POOL_SIZE"},
+		{"port too high", func(c *Config) { c.Server.Port = 70000 }, "PORT"},
+		{"short timeout", func(c *Config) { c.Server.Timeout = Duration{time.Millisecond} }, "SERVER_TIMEOUT"},
+		{"no origins", func(c *Config) { c.Server.CORSOrigins = nil }, "CORS_ORIGINS"},
+		{"recalc without delay", func(c *Config) {
+			c.Features.AutoRecalc, c.Features.RecalcDelay = true, Duration{}
+		}, "RECALC_DELAY"},
+		{"negative proxies", func(c *Config) { c.Server.Proxies = -1 }, "TRUSTED_PROXIES"},
+		{"bad quota", func(c *Config) { c.RateLimits = map[string]Quota{"submit": {1, Duration{time.Second}}} }, "RATE_LIMITS"},
+		{"memory exporter", func(c *Config) { c.Tracing.Exporter = "memory" }, "TRACE_EXPORTER"},
+		{"ratio over 1", func(c *Config) { c.Tracing.SampleRatio = 2 }, "TRACE_SAMPLE_RATIO"},
+		{"bad level", func(c *Config) { c.Logging.Level = "loud" }, "LOG_LEVEL"},
+		{"bad format", func(c *Config) { c.Logging.Format = "xml" }, "LOG_FORMAT"},
+	}
+
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("production defaults with credentials: %v", err)
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			conf := valid()
+			c.change(&conf)
+			if err := conf.Validate(); err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Errorf("got %v, want it to mention %s", err, c.want)
+			}
+		})
+	}
+
+	// everything wrong at once is reported at once
+	err := Config{Profile: "development"}.Validate()
+	if err == nil || strings.Count(err.Error(), ";") < 5 {
+		t.Errorf("got %v, want every problem listed", err)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	conf := Config{Neo4j: Neo4j{URI: "neo4j+s://user:pw@db.example:7687", Password: "secret"}}
+	redacted := conf.Redacted()
+	if redacted.Neo4j.Password != "[redacted]" || strings.Contains(redacted.Neo4j.URI, "pw") {
+		t.Errorf("redacted to %+v", redacted.Neo4j)
+	}
+	if conf.Neo4j.Password != "secret" {
+		t.Error("Redacted changed the original")
+	}
+}
+
+func equalQuotas(a, b map[string]Quota) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for route, quota := range a {
+		if b[route] != quota {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,132 @@
+package config
+
+import "time"
+
+// unauthenticated writes anyone could hammer, /calc rewrites every payout
+var writeLimits = map[string]Quota{
+	"/add_random":   {Requests: 10, Per: Duration{time.Minute}},
+	"/submit":       {Requests: 30, Per: Duration{time.Minute}},
+	"/calc":         {Requests: 6, Per: Duration{time.Minute}},
+	"/delete_model": {Requests: 30, Per: Duration{time.Minute}},
+	"/join":         {Requests: 30, Per: Duration{time.Minute}},
+	"/leave":        {Requests: 30, Per: Duration{time.Minute}},
+	"/space":        {Requests: 10, Per: Duration{time.Minute}},
+	"/resolve":      {Requests: 10, Per: Duration{time.Minute}},
+	"/webhook":      {Requests: 10, Per: Duration{time.Minute}},
+}
+
+type profile struct {
+	credentials string // env prefix for the Neo4j URI, username and password
+	defaults    Config
+}
+
+// profiles selected by APP_ENV
+var profiles = map[string]profile{
+	"development": {
+		credentials: "DB",
+		defaults: Config{
+			Neo4j: Neo4j{
+				MaxPoolSize:           10,
+				AcquireTimeout:        Duration{30 * time.Second},
+				ConnectTimeout:        Duration{5 * time.Second},
+				MaxConnectionLifetime: Duration{time.Hour},
+				MaxRetryTime:          Duration{15 * time.Second},
+				StartupTimeout:        Duration{10 * time.Second},
+			},
+			Server: Server{
+				Host:            "127.0.0.1",
+				Port:            8080,
+				Timeout:         Duration{10 * time.Second},
+				ShutdownTimeout: Duration{20 * time.Second},
+				CORSOrigins:     []string{"*"},
+				IdempotencyTTL:  Duration{24 * time.Hour},
+				Proxies:         0,
+			},
+			Features: Features{
+				RecalcDelay: Duration{2 * time.Second},
+				Bots:        true,
+				Webhooks:    true,
+			},
+			Tracing: Tracing{
+				Exporter:    "none",
+				SampleRatio: 1,
+			},
+			RateLimits: writeLimits,
+			Logging: Logging{
+				Level:  "debug",
+				Format: "text",
+			},
+		},
+	},
+	"staging": {
+		credentials: "AURA",
+		defaults: Config{
+			Neo4j: Neo4j{
+				MaxPoolSize:           50,
+				AcquireTimeout:        Duration{30 * time.Second},
+				ConnectTimeout:        Duration{5 * time.Second},
+				MaxConnectionLifetime: Duration{time.Hour},
+				MaxRetryTime:          Duration{30 * time.Second},
+				StartupTimeout:        Duration{60 * time.Second},
+			},
+			Server: Server{
+				Host:            "0.0.0.0",
+				Port:            5000,
+				Timeout:         Duration{10 * time.Second},
+				ShutdownTimeout: Duration{20 * time.Second},
+				CORSOrigins:     []string{"*"},
+				IdempotencyTTL:  Duration{24 * time.Hour},
+				Proxies:         2, // load balancer and nginx
+			},
+			Features: Features{
+				RecalcDelay: Duration{2 * time.Second},
+				Bots:        true,
+				Webhooks:    true,
+			},
+			Tracing: Tracing{
+				Exporter:    "none",
+				SampleRatio: 1,
+			},
+			RateLimits: writeLimits,
+			Logging: Logging{
+				Level:  "debug",
+				Format: "json",
+			},
+		},
+	},
+	"production": {
+		credentials: "AURA",
+		defaults: Config{
+			Neo4j: Neo4j{
+				MaxPoolSize:           100,
+				AcquireTimeout:        Duration{60 * time.Second},
+				ConnectTimeout:        Duration{5 * time.Second},
+				MaxConnectionLifetime: Duration{time.Hour},
+				MaxRetryTime:          Duration{30 * time.Second},
+				StartupTimeout:        Duration{60 * time.Second},
+			},
+			Server: Server{
+				Host:            "0.0.0.0",
+				Port:            5000, // Elastic Beanstalk's nginx proxies to 5000
+				Timeout:         Duration{10 * time.Second},
+				ShutdownTimeout: Duration{20 * time.Second},
+				CORSOrigins:     []string{"*"},
+				IdempotencyTTL:  Duration{24 * time.Hour},
+				Proxies:         2, // load balancer and nginx
+			},
+			Features: Features{
+				RecalcDelay: Duration{5 * time.Second},
+				Webhooks:    true,
+			},
+			Tracing: Tracing{
+				Exporter:    "none",
+				SampleRatio: 0.1,
+			},
+			RateLimits: writeLimits,
+			Logging: Logging{
+				Level:  "info",
+				Format: "json",
+			},
+		},
+	},
+}
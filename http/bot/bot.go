@@ -0,0 +1,143 @@
+package bot
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"riverboat/model"
+	"time"
+)
+
+// strategy names accepted by Lookup
+const (
+	Uniform        = "uniform"
+	NoisyConsensus = "noisy-consensus"
+	Contrarian     = "contrarian"
+	Copy           = "copy"
+)
+
+// standard deviation, in certainty points, added by noisy-consensus
+const noise = 10.0
+
+// decides what a bot submits given everyone else's models -> name: model
+type Strategy interface {
+	Model(space model.Space, models map[string]map[string]float64) map[string]float64
+}
+
+// target is the name of the player to copy, only used by Copy
+func Lookup(name string, target string) (Strategy, error) {
+	switch name {
+	case Uniform:
+		return uniform{}, nil
+	case NoisyConsensus:
+		return noisyConsensus{rand.New(rand.NewSource(time.Now().UnixNano()))}, nil
+	case Contrarian:
+		return contrarian{}, nil
+	case Copy:
+		if target == "" {
+			return nil, errors.New("copy strategy needs a target player")
+		}
+		return copycat{target}, nil
+	default:
+		return nil, errors.New("unknown strategy: " + name)
+	}
+}
+
+// equal certainty in every field
+type uniform struct{}
+
+func (uniform) Model(space model.Space, models map[string]map[string]float64) map[string]float64 {
+	flat := make(map[string]float64)
+	for _, field := range space.Fields {
+		flat[field] = 1
+	}
+	if independent(space) {
+		for field := range flat {
+			flat[field] = 50
+		}
+	}
+	return normalize(space, flat)
+}
+
+// the crowd's mean certainty with gaussian noise on top
+type noisyConsensus struct {
+	rand *rand.Rand
+}
+
+func (s noisyConsensus) Model(space model.Space, models map[string]map[string]float64) map[string]float64 {
+	if len(models) == 0 {
+		models = map[string]map[string]float64{"": uniform{}.Model(space, nil)}
+	}
+
+	noisy := make(map[string]float64)
+	for field, cert := range consensus(space, models) {
+		noisy[field] = cert + s.rand.NormFloat64()*noise
+	}
+	return normalize(space, noisy)
+}
+
+// bets against the crowd, most certain where everyone else is least
+type contrarian struct{}
+
+func (contrarian) Model(space model.Space, models map[string]map[string]float64) map[string]float64 {
+	if len(models) == 0 {
+		return uniform{}.Model(space, nil)
+	}
+
+	against := make(map[string]float64)
+	for field, cert := range consensus(space, models) {
+		against[field] = 100 - cert
+	}
+	return normalize(space, against)
+}
+
+// submits whatever target submitted, uniform until they do
+type copycat struct {
+	target string
+}
+
+func (s copycat) Model(space model.Space, models map[string]map[string]float64) map[string]float64 {
+	if copied, ok := models[s.target]; ok {
+		return normalize(space, copied)
+	}
+	return uniform{}.Model(space, nil)
+}
+
+// mean certainty per field
+func consensus(space model.Space, models map[string]map[string]float64) map[string]float64 {
+	mean := make(map[string]float64)
+	for _, field := range space.Fields {
+		for _, m := range models {
+			mean[field] += m[field] / float64(len(models))
+		}
+	}
+	return mean
+}
+
+// binary and multi-select fields are each their own yes/no
+func independent(space model.Space) bool {
+	return space.Kind == model.Binary || space.Kind == model.MultiSelect
+}
+
+// clamp to [0, 100] and, for mutually exclusive fields, scale to sum 100
+func normalize(space model.Space, certs map[string]float64) map[string]float64 {
+	total := 0.0
+	clamped := make(map[string]float64)
+	for _, field := range space.Fields {
+		cert := math.Min(math.Max(certs[field], 0), 100)
+		clamped[field] = cert
+		total += cert
+	}
+
+	if independent(space) {
+		return clamped
+	}
+	for field, cert := range clamped {
+		if total == 0 {
+			clamped[field] = 100 / float64(len(clamped))
+		} else {
+			clamped[field] = math.Floor(cert/total*1000) / 10 // one decimal, never past 100
+		}
+	}
+	return clamped
+}
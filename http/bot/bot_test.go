@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"math/rand"
+	"riverboat/model"
+	"testing"
+)
+
+var spaces = []model.Space{
+	{Kind: model.Categorical, Fields: []string{"a", "b", "c"}},
+	{Kind: model.Binary, Fields: []string{"yes"}},
+	{Kind: model.MultiSelect, Fields: []string{"x", "y"}},
+}
+
+var crowd = map[string]map[string]float64{
+	"p1": {"a": 70, "b": 20, "c": 10, "yes": 80, "x": 90, "y": 10},
+	"p2": {"a": 50, "b": 30, "c": 20, "yes": 60, "x": 70, "y": 30},
+}
+
+// whatever the crowd did, a bot only ever submits a model the space accepts
+func TestStrategiesSubmitValidModels(t *testing.T) {
+	strategies := map[string]Strategy{
+		Uniform:        uniform{},
+		NoisyConsensus: noisyConsensus{rand.New(rand.NewSource(1))},
+		Contrarian:     contrarian{},
+		Copy:           copycat{"p1"},
+	}
+
+	for name, strategy := range strategies {
+		for _, space := range spaces {
+			for _, models := range []map[string]map[string]float64{nil, crowd} {
+				certs := strategy.Model(space, models)
+				if err := model.CheckModel(space, certs); err != nil {
+					t.Errorf("%s in a %s space with %d models: %v (%v)", name, space.Kind, len(models), err, certs)
+				}
+			}
+		}
+	}
+}
+
+func TestContrarian(t *testing.T) {
+	certs := contrarian{}.Model(spaces[0], crowd)
+	// the crowd averages a 60, b 25, c 15, so c gets the most
+	if !(certs["c"] > certs["b"] && certs["b"] > certs["a"]) {
+		t.Errorf("got %v, want most on c and least on a", certs)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	certs := copycat{"p2"}.Model(spaces[0], crowd)
+	if certs["a"] != 50 || certs["b"] != 30 || certs["c"] != 20 {
+		t.Errorf("got %v, want p2's model", certs)
+	}
+
+	flat := copycat{"absent"}.Model(spaces[0], crowd)
+	if flat["a"] != flat["b"] || flat["b"] != flat["c"] {
+		t.Errorf("got %v before the target submitted, want uniform", flat)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, name := range []string{Uniform, NoisyConsensus, Contrarian} {
+		if _, err := Lookup(name, ""); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if _, err := Lookup(Copy, ""); err == nil {
+		t.Error("copy without a target was accepted")
+	}
+	if _, err := Lookup("oracle", ""); err == nil {
+		t.Error("an unknown strategy was accepted")
+	}
+}
@@ -10,6 +10,21 @@ type Pair struct {
 	Cert float64
 }
 
+// a single movement of stake from a less certain player to a more certain one
+type Transfer struct {
+	From    string  `json:"from"`
+	To      string  `json:"to"`
+	Amount  float64 `json:"amount"`
+	Tier    int     `json:"tier"`     // trust tier of the payer, 0 is least certain
+	ModLoss float64 `json:"mod_loss"` // certainty of the best remaining player / 100
+}
+
+// payouts along with every transfer that produced them, keyed by field
+type Explanation struct {
+	Payouts   map[string]map[string]float64 `json:"payouts"`
+	Transfers map[string][]Transfer         `json:"transfers"`
+}
+
 // PAYOUTS
 // payouts with this method are calculated to each of person's certainty in the group
 // using a "reverse waterfall" method
@@ -21,12 +36,24 @@ func Payouts(
 	fields []string,
 	stake float64) (map[string]map[string]float64, error) {
 
+	explanation, err := Explain(models, fields, stake)
+	return explanation.Payouts, err
+}
+
+// same as Payouts, but keeps the full breakdown of transfers for auditing
+func Explain(
+	models map[string]map[string]float64,
+	fields []string,
+	stake float64) (Explanation, error) {
+
 	// outcome: { name: payout, ... }
 	outcomeMap := make(map[string]map[string][]float64)
+	transfers := make(map[string][]Transfer)
 	for _, field := range fields {
 		oca := outcomeArray(models, field)
-		pomap := payoutMap(oca, stake)
+		pomap, log := payoutMap(oca, stake)
 		outcomeMap[field] = pomap //outcome_map.insert(String::from(oc), pomap);
+		transfers[field] = log
 	}
 
 	// convert back to original form -> name: { outcome: payout, ... }
@@ -40,7 +67,7 @@ func Payouts(
 		payoutMap[name] = personalMap
 	}
 
-	return payoutMap, nil
+	return Explanation{Payouts: payoutMap, Transfers: transfers}, nil
 }
 
 // generate a vec of tuples -> outcome: [ (name, certainty)... ]
@@ -53,8 +80,12 @@ func outcomeArray(models map[string]map[string]float64, field string) []Pair {
 		oca = append(oca, pair)
 	}
 
+	// ties by name, so map order never changes the sums below
 	sort.Slice(oca, func(i, j int) bool {
-		return oca[i].Cert < oca[j].Cert
+		if oca[i].Cert != oca[j].Cert {
+			return oca[i].Cert < oca[j].Cert
+		}
+		return oca[i].Name < oca[j].Name
 	})
 
 	return oca
@@ -62,7 +93,8 @@ func outcomeArray(models map[string]map[string]float64, field string) []Pair {
 
 // receive the vec of tuples -> outcome: [ (name, certainty), ... ]
 // return hashmap of payouts -> outcome: { name: payout, ... }
-func payoutMap(oca []Pair, stake float64) map[string][]float64 {
+// along with the ordered list of transfers behind them
+func payoutMap(oca []Pair, stake float64) (map[string][]float64, []Transfer) {
 
 	// create empty array for each name: [ payout0, payout1, ... ]
 	var blankMap = func(oca []Pair) map[string][]float64 {
@@ -116,6 +148,8 @@ func payoutMap(oca []Pair, stake float64) map[string][]float64 {
 	}
 
 	pomap := blankMap(oca)
+	var log []Transfer
+	tier := 0
 
 	for len(oca) > 0 {
 		consecutive := getConsecutive(oca)
@@ -138,11 +172,19 @@ func payoutMap(oca []Pair, stake float64) map[string][]float64 {
 				mass := next.Cert / mount
 				portion := payout * mass
 				pomap[next.Name] = append(pomap[next.Name], portion)
+				log = append(log, Transfer{
+					From:    current.Name,
+					To:      next.Name,
+					Amount:  portion,
+					Tier:    tier,
+					ModLoss: modLoss,
+				})
 			}
 		}
+		tier++
 	}
 
-	return pomap
+	return pomap, log
 }
 
 // access vec name: [payouts, ...] and sum all values to get final payout
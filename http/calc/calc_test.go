@@ -0,0 +1,75 @@
+package calc
+
+import (
+	"math"
+	"testing"
+)
+
+var fields = []string{"x", "y"}
+
+var models = map[string]map[string]float64{
+	"a": {"x": 80, "y": 20},
+	"b": {"x": 30, "y": 70},
+	"c": {"x": 50, "y": 50},
+}
+
+// whatever happens, every rule only moves stakes between players
+func TestRulesAreZeroSum(t *testing.T) {
+	for name, rule := range Rules {
+		payouts, err := rule(models, fields, 10)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, field := range fields {
+			sum := 0.0
+			for _, payout := range payouts {
+				sum += payout[field]
+			}
+			if math.Abs(sum) > 1e-9 {
+				t.Errorf("%s: payouts on %s sum to %g", name, field, sum)
+			}
+		}
+	}
+}
+
+// the most certain player on an outcome gains the most when it happens
+func TestRulesRewardCertainty(t *testing.T) {
+	for name, rule := range Rules {
+		payouts, _ := rule(models, fields, 10)
+		if !(payouts["a"]["x"] > payouts["c"]["x"] && payouts["c"]["x"] > payouts["b"]["x"]) {
+			t.Errorf("%s: x paid %v, want a > c > b", name, payouts)
+		}
+	}
+}
+
+func TestParimutuel(t *testing.T) {
+	payouts, _ := Parimutuel(models, fields, 10)
+
+	// 30 in the pot, 16 of it on x, a put 8 there
+	if want := 30*8.0/16 - 10; math.Abs(payouts["a"]["x"]-want) > 1e-9 {
+		t.Errorf("a on x: got %g, want %g", payouts["a"]["x"], want)
+	}
+
+	// nobody backs z, so it refunds everyone
+	payouts, _ = Parimutuel(models, []string{"x", "y", "z"}, 10)
+	for name, payout := range payouts {
+		if payout["z"] != 0 {
+			t.Errorf("%s on unbacked z: got %g, want 0", name, payout["z"])
+		}
+	}
+}
+
+func TestExplainMatchesPayouts(t *testing.T) {
+	payouts, _ := Payouts(models, fields, 10)
+	explanation, _ := Explain(models, fields, 10)
+	for name := range models {
+		for _, field := range fields {
+			if explanation.Payouts[name][field] != payouts[name][field] {
+				t.Errorf("%s on %s: explained %g, paid %g", name, field, explanation.Payouts[name][field], payouts[name][field])
+			}
+		}
+	}
+	if len(explanation.Transfers["x"]) == 0 {
+		t.Error("no transfers explained on x")
+	}
+}
@@ -0,0 +1,141 @@
+package calc
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// aggregation methods accepted by Consensus
+const (
+	Mean    = "mean"
+	Median  = "median"
+	Trimmed = "trimmed"
+	LogOdds = "logodds"
+)
+
+// clamp used before taking log-odds so 0 and 100 stay finite
+const logOddsClamp = 0.01
+
+type Dispersion struct {
+	StdDev float64 `json:"stddev"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	IQR    float64 `json:"iqr"`
+}
+
+type Crowd struct {
+	Method       string                `json:"method"`
+	Contributors int                   `json:"contributors"`
+	Distribution map[string]float64    `json:"distribution"`
+	Dispersion   map[string]Dispersion `json:"dispersion"`
+}
+
+// aggregate every player's certainty in each field into one crowd forecast
+// trim is the fraction cut from each end for Trimmed, weights -> name: weight
+// are only used by LogOdds and default to 1 for anyone missing
+func Consensus(
+	models map[string]map[string]float64,
+	fields []string,
+	method string,
+	trim float64,
+	weights map[string]float64) (Crowd, error) {
+
+	crowd := Crowd{
+		Method:       method,
+		Contributors: len(models),
+		Distribution: make(map[string]float64),
+		Dispersion:   make(map[string]Dispersion),
+	}
+
+	for _, field := range fields {
+		oca := outcomeArray(models, field) // sorted by certainty
+		certs := make([]float64, len(oca))
+		for i, pair := range oca {
+			certs[i] = pair.Cert
+		}
+
+		switch method {
+		case Mean:
+			crowd.Distribution[field] = mean(certs)
+		case Median:
+			crowd.Distribution[field] = quantile(certs, 0.5)
+		case Trimmed:
+			cut := int(float64(len(certs)) * trim)
+			if 2*cut >= len(certs) {
+				cut = (len(certs) - 1) / 2
+			}
+			crowd.Distribution[field] = mean(certs[cut : len(certs)-cut])
+		case LogOdds:
+			crowd.Distribution[field] = logOddsPool(oca, weights)
+		default:
+			return Crowd{}, errors.New("unknown consensus method: " + method)
+		}
+
+		crowd.Dispersion[field] = dispersion(certs)
+	}
+
+	return crowd, nil
+}
+
+// weighted mean in log-odds space, mapped back to a percentage
+func logOddsPool(oca []Pair, weights map[string]float64) float64 {
+	total, sum := 0.0, 0.0
+	for _, pair := range oca {
+		weight, ok := weights[pair.Name]
+		if !ok {
+			weight = 1
+		}
+		p := math.Min(math.Max(pair.Cert/100, logOddsClamp), 1-logOddsClamp)
+		sum += weight * math.Log(p/(1-p))
+		total += weight
+	}
+	if total == 0 {
+		return 0
+	}
+	return 100 / (1 + math.Exp(-sum/total))
+}
+
+// expects sorted values
+func dispersion(certs []float64) Dispersion {
+	if len(certs) == 0 {
+		return Dispersion{}
+	}
+
+	avg := mean(certs)
+	variance := 0.0
+	for _, cert := range certs {
+		variance += (cert - avg) * (cert - avg)
+	}
+
+	return Dispersion{
+		StdDev: math.Sqrt(variance / float64(len(certs))),
+		Min:    certs[0],
+		Max:    certs[len(certs)-1],
+		IQR:    quantile(certs, 0.75) - quantile(certs, 0.25),
+	}
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	return sumPayouts(values) / float64(len(values))
+}
+
+// linear interpolation between closest ranks, expects sorted values
+func quantile(values []float64, q float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	if !sort.Float64sAreSorted(values) {
+		values = append([]float64(nil), values...)
+		sort.Float64s(values)
+	}
+
+	pos := q * float64(len(values)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	frac := pos - float64(lower)
+	return values[lower] + (values[upper]-values[lower])*frac
+}
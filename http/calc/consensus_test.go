@@ -0,0 +1,76 @@
+package calc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConsensus(t *testing.T) {
+	cases := []struct {
+		method  string
+		trim    float64
+		weights map[string]float64
+		x       float64
+	}{
+		{Mean, 0, nil, 160.0 / 3},
+		{Median, 0, nil, 50},
+		{Trimmed, 0, nil, 160.0 / 3},
+		{Trimmed, 0.34, nil, 50},      // one cut from each end
+		{Trimmed, 0.5, nil, 50},       // never cuts everyone
+		{LogOdds, 0, nil, 54.4795940}, // logit(.3), logit(.5), logit(.8) averaged
+		{LogOdds, 0, map[string]float64{"a": 2, "b": 1}, 61.8060166},
+		{LogOdds, 0, map[string]float64{"c": 0}, 56.6969722}, // c drops out
+	}
+	for _, c := range cases {
+		crowd, err := Consensus(models, fields, c.method, c.trim, c.weights)
+		if err != nil {
+			t.Fatalf("%s: %v", c.method, err)
+		}
+		if crowd.Method != c.method || crowd.Contributors != 3 {
+			t.Errorf("%s: crowd %+v", c.method, crowd)
+		}
+		if got := crowd.Distribution["x"]; math.Abs(got-c.x) > 1e-6 {
+			t.Errorf("%s trim %g weights %v: x at %g, want %g", c.method, c.trim, c.weights, got, c.x)
+		}
+	}
+}
+
+func TestConsensusDispersion(t *testing.T) {
+	crowd, _ := Consensus(models, fields, Mean, 0, nil)
+
+	// x is 30, 50 and 80
+	got := crowd.Dispersion["x"]
+	want := Dispersion{StdDev: math.Sqrt(3800.0 / 9), Min: 30, Max: 80, IQR: 65 - 40}
+	if math.Abs(got.StdDev-want.StdDev) > 1e-9 || got.Min != want.Min || got.Max != want.Max || got.IQR != want.IQR {
+		t.Errorf("x dispersion %+v, want %+v", got, want)
+	}
+}
+
+func TestConsensusEdges(t *testing.T) {
+	for _, method := range []string{Mean, Median, Trimmed, LogOdds} {
+		crowd, err := Consensus(map[string]map[string]float64{}, fields, method, 0.25, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		if crowd.Contributors != 0 || crowd.Distribution["x"] != 0 || crowd.Dispersion["x"] != (Dispersion{}) {
+			t.Errorf("%s with no models: %+v", method, crowd)
+		}
+
+		single := map[string]map[string]float64{"a": {"x": 100, "y": 0}}
+		crowd, err = Consensus(single, fields, method, 0.5, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		want := 100.0
+		if method == LogOdds {
+			want = 100 * (1 - logOddsClamp) // certainty is clamped to stay finite
+		}
+		if math.Abs(crowd.Distribution["x"]-want) > 1e-9 || crowd.Dispersion["x"] != (Dispersion{Min: 100, Max: 100}) {
+			t.Errorf("%s with one model: %+v", method, crowd)
+		}
+	}
+
+	if _, err := Consensus(models, fields, "mode", 0, nil); err == nil {
+		t.Error("an unknown method was accepted")
+	}
+}
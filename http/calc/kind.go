@@ -0,0 +1,112 @@
+package calc
+
+import (
+	"riverboat/model"
+	"time"
+)
+
+// when set, called after every payout computation with its size and duration
+var Observe func(kind string, players int, fields int, elapsed time.Duration)
+
+// one categorical waterfall: the fields it covers and everyone's certainty in them
+type book struct {
+	fields []string
+	models map[string]map[string]float64
+}
+
+// payouts for any kind of space under the rule its pattern names
+// -> name: { outcome: payout, ... }
+// binary and multi-select fields also get a field+model.NoSuffix outcome
+func PayoutsFor(
+	kind string,
+	pattern string,
+	models map[string]map[string]float64,
+	fields []string,
+	stake float64) (map[string]map[string]float64, error) {
+
+	explanation, err := ExplainFor(kind, pattern, models, fields, stake)
+	return explanation.Payouts, err
+}
+
+// Explain for any kind of space, merging the breakdown of every book;
+// only waterfall moves money in transfers, other rules explain no more
+// than their payouts. Spaces from before patterns picked a rule, or
+// naming one no longer known, are scored by waterfall
+func ExplainFor(
+	kind string,
+	pattern string,
+	models map[string]map[string]float64,
+	fields []string,
+	stake float64) (Explanation, error) {
+
+	explain := Explain
+	if rule, ok := Rules[pattern]; ok && pattern != WaterfallPattern {
+		explain = func(models map[string]map[string]float64, fields []string, stake float64) (Explanation, error) {
+			payouts, err := rule(models, fields, stake)
+			return Explanation{Payouts: payouts}, err
+		}
+	}
+
+	if Observe != nil {
+		start := time.Now()
+		defer func() { Observe(kind, len(models), len(fields), time.Since(start)) }()
+	}
+
+	merged := Explanation{
+		Payouts:   make(map[string]map[string]float64),
+		Transfers: make(map[string][]Transfer),
+	}
+	for name := range models {
+		merged.Payouts[name] = make(map[string]float64)
+	}
+
+	for _, b := range books(kind, models, fields) {
+		explanation, err := explain(b.models, b.fields, stake)
+		if err != nil {
+			return Explanation{}, err
+		}
+		for name, payouts := range explanation.Payouts {
+			for field, payout := range payouts {
+				merged.Payouts[name][field] = payout
+			}
+		}
+		for field, transfers := range explanation.Transfers {
+			merged.Transfers[field] = transfers
+		}
+	}
+
+	return merged, nil
+}
+
+// split a space into the categorical books its kind is scored as
+func books(kind string, models map[string]map[string]float64, fields []string) []book {
+	switch kind {
+	case model.Binary:
+		if len(fields) == 0 {
+			return nil
+		}
+		return []book{yesNo(models, fields[0])}
+	case model.MultiSelect:
+		var yesNos []book
+		for _, field := range fields {
+			yesNos = append(yesNos, yesNo(models, field))
+		}
+		return yesNos
+	default: // categorical and numeric buckets
+		return []book{{fields: fields, models: models}}
+	}
+}
+
+// treat a single probability as a two-field distribution with its complement
+func yesNo(models map[string]map[string]float64, field string) book {
+	no := field + model.NoSuffix
+
+	split := make(map[string]map[string]float64)
+	for name, m := range models {
+		split[name] = map[string]float64{
+			field: m[field],
+			no:    100 - m[field],
+		}
+	}
+	return book{fields: []string{field, no}, models: split}
+}
@@ -0,0 +1,84 @@
+package calc
+
+import (
+	"math"
+	"riverboat/model"
+	"testing"
+)
+
+// every outcome of a binary or multi-select space, including each
+// field's no, pays out exactly what the pot took in
+func TestYesNoKindsConservePot(t *testing.T) {
+	cases := []struct {
+		kind     string
+		fields   []string
+		outcomes []string
+	}{
+		{model.Binary, []string{"x"}, []string{"x", "x:no"}},
+		{model.MultiSelect, fields, []string{"x", "x:no", "y", "y:no"}},
+	}
+	for _, c := range cases {
+		for pattern := range Rules {
+			payouts, err := PayoutsFor(c.kind, pattern, models, c.fields, 10)
+			if err != nil {
+				t.Fatalf("%s %s: %v", c.kind, pattern, err)
+			}
+			for _, outcome := range c.outcomes {
+				sum := 0.0
+				for name, payout := range payouts {
+					if _, ok := payout[outcome]; !ok {
+						t.Errorf("%s %s: %s has no payout on %s", c.kind, pattern, name, outcome)
+					}
+					sum += payout[outcome]
+				}
+				if math.Abs(sum) > 1e-9 {
+					t.Errorf("%s %s: payouts on %s sum to %g", c.kind, pattern, outcome, sum)
+				}
+			}
+		}
+	}
+}
+
+// a field's no is scored as the complement of everyone's certainty in it
+func TestNoOutcome(t *testing.T) {
+	complement := make(map[string]map[string]float64)
+	for name, m := range models {
+		complement[name] = map[string]float64{"x": m["x"], "x" + model.NoSuffix: 100 - m["x"]}
+	}
+	want, _ := PayoutsFor(model.Categorical, WaterfallPattern, complement, []string{"x", "x:no"}, 10)
+
+	binary, _ := PayoutsFor(model.Binary, WaterfallPattern, models, []string{"x"}, 10)
+	multi, _ := PayoutsFor(model.MultiSelect, WaterfallPattern, models, fields, 10)
+	for name := range models {
+		for _, outcome := range []string{"x", "x:no"} {
+			if binary[name][outcome] != want[name][outcome] || multi[name][outcome] != want[name][outcome] {
+				t.Errorf("%s on %s: binary %g, multi %g, want %g",
+					name, outcome, binary[name][outcome], multi[name][outcome], want[name][outcome])
+			}
+		}
+	}
+
+	// whoever was least sure of x gains most when it doesn't happen
+	if !(binary["b"]["x:no"] > binary["c"]["x:no"] && binary["c"]["x:no"] > binary["a"]["x:no"]) {
+		t.Errorf("x:no paid %v, want b > c > a", binary)
+	}
+}
+
+// multi-select fields are independent, so certainty in one moves nothing in another
+func TestMultiSelectBooksAreIndependent(t *testing.T) {
+	before, _ := PayoutsFor(model.MultiSelect, WaterfallPattern, models, fields, 10)
+
+	changed := make(map[string]map[string]float64)
+	for name, m := range models {
+		changed[name] = map[string]float64{"x": 100 - m["x"], "y": m["y"]}
+	}
+	after, _ := PayoutsFor(model.MultiSelect, WaterfallPattern, changed, fields, 10)
+
+	for name := range models {
+		for _, outcome := range []string{"y", "y:no"} {
+			if before[name][outcome] != after[name][outcome] {
+				t.Errorf("%s on %s moved from %g to %g", name, outcome, before[name][outcome], after[name][outcome])
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package calc
+
+import "sort"
+
+// PARIMUTUEL
+// each player spreads their stake over the fields in proportion to their
+// certainty, and the whole pot goes to whoever backed the outcome, split by
+// how much each put on it; nobody backing an outcome refunds everyone
+
+// receives hashmap of prediction models -> name: { outcome: certainty, ... }
+// returns hashmap of payouts -> name: { outcome: payout, ... }
+func Parimutuel(
+	models map[string]map[string]float64,
+	fields []string,
+	stake float64) (map[string]map[string]float64, error) {
+
+	// in name order, so map order never changes the sums
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// name: { outcome: amount bet, ... }
+	bets := make(map[string]map[string]float64)
+	backed := make(map[string]float64) // outcome: total bet on it
+	pot := 0.0
+	for _, name := range names {
+		model := models[name]
+		total := 0.0
+		for _, field := range fields {
+			total += model[field]
+		}
+		bets[name] = make(map[string]float64)
+		if total <= 0 {
+			continue // nothing to spread, keeps their stake out of the pot
+		}
+		for _, field := range fields {
+			bet := stake * model[field] / total
+			bets[name][field] = bet
+			backed[field] += bet
+		}
+		pot += stake
+	}
+
+	payoutMap := make(map[string]map[string]float64)
+	for name := range models {
+		personalMap := make(map[string]float64)
+		in := 0.0
+		for _, field := range fields {
+			in += bets[name][field]
+		}
+		for _, field := range fields {
+			if backed[field] > 0 {
+				personalMap[field] = pot*bets[name][field]/backed[field] - in
+			}
+		}
+		payoutMap[name] = personalMap
+	}
+
+	return payoutMap, nil
+}
@@ -0,0 +1,19 @@
+package calc
+
+// a categorical scoring rule -> name: { outcome: payout, ... }
+type Rule func(
+	models map[string]map[string]float64,
+	fields []string,
+	stake float64) (map[string]map[string]float64, error)
+
+// pattern names a space can be scored by
+const (
+	WaterfallPattern  = "waterfall"
+	ParimutuelPattern = "parimutuel"
+)
+
+// every scoring rule, keyed by the pattern name a space would use
+var Rules = map[string]Rule{
+	WaterfallPattern:  Payouts,
+	ParimutuelPattern: Parimutuel,
+}
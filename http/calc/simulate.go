@@ -0,0 +1,46 @@
+package calc
+
+// payouts before and after a hypothetical model -> name: { outcome: payout, ... }
+type Simulation struct {
+	Current   map[string]map[string]float64 `json:"current"`
+	Projected map[string]map[string]float64 `json:"projected"`
+	Delta     map[string]map[string]float64 `json:"delta"`
+}
+
+// what payouts would look like if name submitted hypothetical,
+// leaving models untouched
+func Simulate(
+	kind string,
+	pattern string,
+	models map[string]map[string]float64,
+	fields []string,
+	stake float64,
+	name string,
+	hypothetical map[string]float64) (Simulation, error) {
+
+	current, err := PayoutsFor(kind, pattern, models, fields, stake)
+	if err != nil {
+		return Simulation{}, err
+	}
+
+	merged := make(map[string]map[string]float64)
+	for player, model := range models {
+		merged[player] = model
+	}
+	merged[name] = hypothetical
+
+	projected, err := PayoutsFor(kind, pattern, merged, fields, stake)
+	if err != nil {
+		return Simulation{}, err
+	}
+
+	delta := make(map[string]map[string]float64)
+	for player, payouts := range projected {
+		delta[player] = make(map[string]float64)
+		for outcome, payout := range payouts {
+			delta[player][outcome] = payout - current[player][outcome]
+		}
+	}
+
+	return Simulation{Current: current, Projected: projected, Delta: delta}, nil
+}
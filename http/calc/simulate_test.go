@@ -0,0 +1,54 @@
+package calc
+
+import (
+	"math"
+	"riverboat/model"
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	hypothetical := map[string]float64{"x": 10, "y": 90}
+
+	for pattern, rule := range Rules {
+		simulation, err := Simulate(model.Categorical, pattern, models, fields, 10, "c", hypothetical)
+		if err != nil {
+			t.Fatalf("%s: %v", pattern, err)
+		}
+
+		current, _ := rule(models, fields, 10)
+		merged := map[string]map[string]float64{"a": models["a"], "b": models["b"], "c": hypothetical}
+		projected, _ := rule(merged, fields, 10)
+
+		for name := range models {
+			for _, field := range fields {
+				if math.Abs(simulation.Current[name][field]-current[name][field]) > 1e-9 {
+					t.Errorf("%s: current %s on %s is %g, the rule pays %g", pattern, name, field, simulation.Current[name][field], current[name][field])
+				}
+				if math.Abs(simulation.Projected[name][field]-projected[name][field]) > 1e-9 {
+					t.Errorf("%s: projected %s on %s is %g, the rule pays %g", pattern, name, field, simulation.Projected[name][field], projected[name][field])
+				}
+				if want := projected[name][field] - current[name][field]; math.Abs(simulation.Delta[name][field]-want) > 1e-9 {
+					t.Errorf("%s: delta %s on %s is %g, want %g", pattern, name, field, simulation.Delta[name][field], want)
+				}
+			}
+		}
+	}
+
+	if models["c"]["x"] != 50 {
+		t.Error("Simulate changed the models it was given")
+	}
+}
+
+// the same simulation under two patterns differs, so the pattern is used
+func TestSimulateUsesPattern(t *testing.T) {
+	hypothetical := map[string]float64{"x": 10, "y": 90}
+	waterfall, _ := Simulate(model.Categorical, WaterfallPattern, models, fields, 10, "c", hypothetical)
+	parimutuel, _ := Simulate(model.Categorical, ParimutuelPattern, models, fields, 10, "c", hypothetical)
+
+	if math.Abs(waterfall.Projected["c"]["y"]-parimutuel.Projected["c"]["y"]) < 1e-9 {
+		t.Error("waterfall and parimutuel simulated the same payouts")
+	}
+	if legacy, _ := Simulate(model.Categorical, "", models, fields, 10, "c", hypothetical); legacy.Projected["c"]["y"] != waterfall.Projected["c"]["y"] {
+		t.Error("a space without a pattern was not scored by waterfall")
+	}
+}
@@ -0,0 +1,169 @@
+package hub
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// event kinds published by the route handlers
+const (
+	ModelSubmitted  = "model.submitted"
+	ModelDeleted    = "model.deleted"
+	PlayerJoined    = "player.joined"
+	PlayerLeft      = "player.left"
+	PayoutsComputed = "payouts.computed"
+	SpaceResolved   = "space.resolved"
+	SpaceCreated    = "space.created"
+)
+
+// topics with a backlog at once, the one published to least recently goes
+const maxTopics = 4096
+
+// ID is "<boot epoch>-<sequence>", so an ID handed out before a restart is
+// never mistaken for one of the new process's
+type Event struct {
+	ID    string      `json:"id"`
+	Topic string      `json:"topic"`
+	Kind  string      `json:"kind"`
+	Data  interface{} `json:"data"`
+	seq   uint64
+}
+
+// in-process pub/sub keyed by topic (a circle or space uuid)
+// keeps the most recent events of each topic so reconnecting clients can resume
+type Hub struct {
+	mu        sync.Mutex
+	epoch     string
+	next      uint64
+	backlogs  map[string][]Event
+	size      int
+	subs      map[string]map[chan Event]struct{}
+	listeners []func(Event)
+	done      chan struct{}
+}
+
+// size is the number of past events kept for replay on each topic
+func New(size int) *Hub {
+	return &Hub{
+		epoch:    strconv.FormatInt(time.Now().UnixNano(), 36),
+		backlogs: make(map[string][]Event),
+		size:     size,
+		subs:     make(map[string]map[chan Event]struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// closed by Close so long-lived subscribers can hang up before shutdown
+func (h *Hub) Done() <-chan struct{} {
+	return h.done
+}
+
+// tell every subscriber the server is going away, safe to call more than once
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	select {
+	case <-h.done:
+	default:
+		close(h.done)
+	}
+}
+
+// register fn to receive every event on every topic, called by the publisher
+// once the event is fanned out, so it should not block for long
+func (h *Hub) Listen(fn func(Event)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.listeners = append(h.listeners, fn)
+}
+
+// fan an event out to every subscriber of topic, then to the listeners
+// slow subscribers miss live events rather than block the publisher
+func (h *Hub) Publish(topic string, kind string, data interface{}) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+
+	h.next++
+	event := Event{
+		ID:    h.epoch + "-" + strconv.FormatUint(h.next, 10),
+		Topic: topic,
+		Kind:  kind,
+		Data:  data,
+		seq:   h.next,
+	}
+
+	if _, ok := h.backlogs[topic]; !ok && len(h.backlogs) >= maxTopics {
+		h.evict()
+	}
+	backlog := append(h.backlogs[topic], event)
+	if len(backlog) > h.size {
+		backlog = backlog[len(backlog)-h.size:]
+	}
+	h.backlogs[topic] = backlog
+
+	for ch := range h.subs[topic] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+	listeners := h.listeners
+	h.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(event)
+	}
+}
+
+// drop the backlog of the topic published to least recently
+func (h *Hub) evict() {
+	var oldest string
+	var seq uint64
+	for topic, backlog := range h.backlogs {
+		if last := backlog[len(backlog)-1].seq; oldest == "" || last < seq {
+			oldest, seq = topic, last
+		}
+	}
+	delete(h.backlogs, oldest)
+}
+
+// returns the events on topic after lastID still in the backlog, all of
+// them when lastID came from before a restart, a channel of live events
+// and a func to unsubscribe
+func (h *Hub) Subscribe(topic string, lastID string) ([]Event, <-chan Event, func()) {
+	ch := make(chan Event, 32)
+
+	h.mu.Lock()
+	var after uint64
+	if epoch, seq, ok := strings.Cut(lastID, "-"); ok && epoch == h.epoch {
+		after, _ = strconv.ParseUint(seq, 10, 64)
+	}
+	var missed []Event
+	for _, event := range h.backlogs[topic] {
+		if event.seq > after {
+			missed = append(missed, event)
+		}
+	}
+	if h.subs[topic] == nil {
+		h.subs[topic] = make(map[chan Event]struct{})
+	}
+	h.subs[topic][ch] = struct{}{}
+	h.mu.Unlock()
+
+	cancel := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subs[topic], ch)
+		if len(h.subs[topic]) == 0 {
+			delete(h.subs, topic)
+		}
+	}
+
+	return missed, ch, cancel
+}
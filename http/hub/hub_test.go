@@ -0,0 +1,101 @@
+package hub
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestReplayAfterLastID(t *testing.T) {
+	h := New(10)
+	h.Publish("s1", ModelSubmitted, 1)
+	h.Publish("s1", ModelSubmitted, 2)
+	h.Publish("s1", ModelSubmitted, 3)
+
+	all, _, cancel := h.Subscribe("s1", "")
+	cancel()
+	if len(all) != 3 {
+		t.Fatalf("replayed %d events with no Last-Event-ID, want 3", len(all))
+	}
+
+	missed, _, cancel := h.Subscribe("s1", all[0].ID)
+	cancel()
+	if len(missed) != 2 || missed[0].Data != 2 {
+		t.Errorf("replayed %v after the first event, want the last two", missed)
+	}
+}
+
+func TestIDsFromBeforeRestart(t *testing.T) {
+	before := New(10)
+	for i := 0; i < 5; i++ {
+		before.Publish("s1", ModelSubmitted, i)
+	}
+	_, _, cancel := before.Subscribe("s1", "")
+	cancel()
+
+	// a new process numbers from 1 again, the old ID must not hide its events
+	after := New(10)
+	after.epoch = before.epoch + "x"
+	after.Publish("s1", ModelSubmitted, "fresh")
+
+	missed, _, cancel := after.Subscribe("s1", before.epoch+"-5")
+	cancel()
+	if len(missed) != 1 || missed[0].Data != "fresh" {
+		t.Errorf("replayed %v to a client from the last boot, want the fresh event", missed)
+	}
+}
+
+func TestBacklogPerTopic(t *testing.T) {
+	h := New(2)
+	h.Publish("quiet", SpaceCreated, "kept")
+	for i := 0; i < 10; i++ {
+		h.Publish("busy", ModelSubmitted, i) // must not push quiet's event out
+	}
+
+	missed, _, cancel := h.Subscribe("quiet", "")
+	cancel()
+	if len(missed) != 1 || missed[0].Data != "kept" {
+		t.Errorf("quiet topic replayed %v, want its one event", missed)
+	}
+	busy, _, cancel := h.Subscribe("busy", "")
+	cancel()
+	if len(busy) != 2 || busy[1].Data != 9 {
+		t.Errorf("busy topic replayed %v, want its last two", busy)
+	}
+}
+
+func TestEvictLeastRecentTopic(t *testing.T) {
+	h := New(1)
+	for i := 0; i < maxTopics+1; i++ {
+		h.Publish("t"+strconv.Itoa(i), SpaceCreated, i)
+	}
+
+	if len(h.backlogs) != maxTopics {
+		t.Errorf("kept %d topics, want %d", len(h.backlogs), maxTopics)
+	}
+	if _, ok := h.backlogs["t0"]; ok {
+		t.Error("the least recent topic was not evicted")
+	}
+}
+
+func TestPublishLive(t *testing.T) {
+	h := New(10)
+	_, events, cancel := h.Subscribe("c1", "")
+	defer cancel()
+
+	var heard []Event
+	h.Listen(func(event Event) { heard = append(heard, event) })
+	h.Publish("c1", PlayerJoined, nil)
+	h.Publish("c2", PlayerJoined, nil)
+
+	if event := <-events; event.Topic != "c1" {
+		t.Errorf("subscriber got %s, want c1", event.Topic)
+	}
+	select {
+	case event := <-events:
+		t.Errorf("subscriber to c1 got an event on %s", event.Topic)
+	default:
+	}
+	if len(heard) != 2 {
+		t.Errorf("listener heard %d events, want every topic's", len(heard))
+	}
+}
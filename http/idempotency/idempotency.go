@@ -0,0 +1,254 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"riverboat/http/limit"
+	"sync"
+	"time"
+
+	"goyave.dev/goyave/v4"
+)
+
+// header clients send with a unique value per logical request
+const Header = "Idempotency-Key"
+
+// set on replayed responses so clients can tell
+const ReplayHeader = "Idempotent-Replayed"
+
+// the first complete response for a key
+type Record struct {
+	Fingerprint string // hash of the request that made it
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// responses by key, implemented in memory here and by anything shared that
+// several instances should agree on
+type Store interface {
+	// claim key for a new request (true), or return the record to replay
+	// (false), waiting while another request holds the claim
+	Begin(ctx context.Context, key string, fingerprint string) (Record, bool, error)
+	// store the response for a claimed key until ttl passes
+	Finish(key string, record Record, ttl time.Duration)
+	// release a claimed key without storing anything, so retries run again
+	Abandon(key string)
+}
+
+type entry struct {
+	record  Record
+	expires time.Time
+	done    chan struct{} // closed once the claim is finished or abandoned
+}
+
+// Store for a single instance
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	swept   time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]*entry)}
+}
+
+func (m *Memory) Begin(ctx context.Context, key string, fingerprint string) (Record, bool, error) {
+	for {
+		m.mu.Lock()
+		now := time.Now()
+		if now.Sub(m.swept) > time.Minute {
+			m.sweep(now)
+		}
+
+		e, ok := m.entries[key]
+		if !ok || (e.record.Status != 0 && now.After(e.expires)) {
+			m.entries[key] = &entry{record: Record{Fingerprint: fingerprint}, done: make(chan struct{})}
+			m.mu.Unlock()
+			return Record{}, true, nil
+		}
+		if e.record.Status != 0 {
+			m.mu.Unlock()
+			return e.record, false, nil
+		}
+		if e.record.Fingerprint != fingerprint {
+			m.mu.Unlock()
+			return e.record, false, nil // mismatch is reported without waiting
+		}
+		done := e.done
+		m.mu.Unlock()
+
+		select {
+		case <-done: // look again, it was either finished or abandoned
+		case <-ctx.Done():
+			return Record{}, false, ctx.Err()
+		}
+	}
+}
+
+func (m *Memory) Finish(key string, record Record, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[key]; ok {
+		e.record, e.expires = record, time.Now().Add(ttl)
+		close(e.done)
+	}
+}
+
+func (m *Memory) Abandon(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[key]; ok {
+		delete(m.entries, key)
+		close(e.done)
+	}
+}
+
+func (m *Memory) sweep(now time.Time) {
+	for key, e := range m.entries {
+		if e.record.Status != 0 && now.After(e.expires) {
+			delete(m.entries, key)
+		}
+	}
+	m.swept = now
+}
+
+// replays responses to POST requests carrying Idempotency-Key, scoped to
+// the caller and route
+type Keys struct {
+	Store   Store
+	TTL     time.Duration
+	Proxies int // as for limit.ClientIP
+}
+
+func (k Keys) Middleware(next goyave.Handler) goyave.Handler {
+	return func(response *goyave.Response, r *goyave.Request) {
+		id := r.Header().Get(Header)
+		if r.Method() != http.MethodPost || id == "" {
+			next(response, r)
+			return
+		}
+		if len(id) > 255 {
+			response.String(http.StatusBadRequest, "Error: Idempotency-Key is too long.") // 400
+			return
+		}
+
+		route := r.URI().Path
+		if r.Route() != nil {
+			route = r.Route().GetFullURI()
+		}
+		puuid, _ := r.Data["puuid"].(string)
+		key := scope(limit.ClientIP(r, k.Proxies), puuid, route, id)
+		fingerprint := fingerprint(r)
+
+		record, claimed, err := k.Store.Begin(r.Request().Context(), key, fingerprint)
+		if err != nil {
+			response.String(http.StatusServiceUnavailable, "Error: Could not check Idempotency-Key.") // 503
+			return
+		}
+		switch verdict(record, claimed, fingerprint) {
+		case mismatched:
+			response.String(http.StatusUnprocessableEntity, "Error: Idempotency-Key was used for a different request.") // 422
+			return
+		case replayed:
+			replay(response, record)
+			return
+		}
+
+		recorded := &recorder{Writer: response.GetWriter()}
+		response.SetWriter(recorded)
+
+		finished := false
+		defer func() {
+			if !finished {
+				k.Store.Abandon(key) // the handler panicked
+			}
+		}()
+
+		next(response, r)
+		finished = true
+
+		status := response.GetStatus()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status >= 500 {
+			k.Store.Abandon(key) // nothing we'd want to replay
+			return
+		}
+		k.Store.Finish(key, Record{
+			Fingerprint: fingerprint,
+			Status:      status,
+			ContentType: response.Header().Get("Content-Type"),
+			Body:        recorded.body.Bytes(),
+		}, k.TTL)
+	}
+}
+
+// the store key for a request; the client IP is always part of it, since
+// a puuid in the body is only a claim and must not reach another caller's
+// stored responses
+func scope(ip string, puuid string, route string, id string) string {
+	caller := "ip:" + ip
+	if puuid != "" {
+		caller += "|player:" + puuid
+	}
+	return caller + "|" + route + "|" + id
+}
+
+// what Begin's answer means for the request
+const (
+	fresh      = iota // run the handler
+	replayed          // send the stored response
+	mismatched        // the key belongs to a different request
+)
+
+func verdict(record Record, claimed bool, fingerprint string) int {
+	switch {
+	case claimed:
+		return fresh
+	case record.Fingerprint != fingerprint:
+		return mismatched
+	}
+	return replayed
+}
+
+func replay(response *goyave.Response, record Record) {
+	if record.ContentType != "" {
+		response.Header().Set("Content-Type", record.ContentType)
+	}
+	response.Header().Set(ReplayHeader, "true")
+	response.Status(record.Status)
+	response.Write(record.Body)
+}
+
+// hash of the parsed request data, which encoding/json writes with sorted keys
+func fingerprint(r *goyave.Request) string {
+	data, _ := json.Marshal(r.Data)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// copies everything the handler writes while passing it on
+type recorder struct {
+	io.Writer
+	body bytes.Buffer
+}
+
+func (rw *recorder) Write(b []byte) (int, error) {
+	rw.body.Write(b)
+	return rw.Writer.Write(b)
+}
+
+// let goyave close the writer it wraps
+func (rw *recorder) Close() error {
+	if closer, ok := rw.Writer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	store := NewMemory()
+	ctx := context.Background()
+
+	if _, claimed, _ := store.Begin(ctx, "k", "fp"); !claimed {
+		t.Fatal("first request did not claim the key")
+	}
+	store.Finish("k", Record{Fingerprint: "fp", Status: 201, Body: []byte("made")}, time.Minute)
+
+	record, claimed, err := store.Begin(ctx, "k", "fp")
+	if err != nil || verdict(record, claimed, "fp") != replayed {
+		t.Fatalf("repeat: claimed=%v err=%v, want a replay", claimed, err)
+	}
+	if record.Status != 201 || string(record.Body) != "made" {
+		t.Errorf("replayed %d %q, want 201 made", record.Status, record.Body)
+	}
+}
+
+func TestFingerprintMismatch(t *testing.T) {
+	store := NewMemory()
+	ctx := context.Background()
+
+	store.Begin(ctx, "k", "fp")
+	// reported straight away while the first request is still running
+	record, claimed, _ := store.Begin(ctx, "k", "other")
+	if verdict(record, claimed, "other") != mismatched {
+		t.Error("different request under an in-flight key was not a mismatch")
+	}
+
+	store.Finish("k", Record{Fingerprint: "fp", Status: 200}, time.Minute)
+	record, claimed, _ = store.Begin(ctx, "k", "other")
+	if verdict(record, claimed, "other") != mismatched {
+		t.Error("different request under a finished key was not a mismatch")
+	}
+}
+
+func TestConcurrentDuplicates(t *testing.T) {
+	store := NewMemory()
+	ctx := context.Background()
+
+	store.Begin(ctx, "k", "fp")
+
+	var wg sync.WaitGroup
+	verdicts := make(chan int, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			record, claimed, err := store.Begin(ctx, "k", "fp")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			verdicts <- verdict(record, claimed, "fp")
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond) // let the duplicates queue up on the claim
+	store.Finish("k", Record{Fingerprint: "fp", Status: 200}, time.Minute)
+	wg.Wait()
+	close(verdicts)
+
+	for v := range verdicts {
+		if v != replayed {
+			t.Errorf("duplicate got verdict %d, want a replay of the first response", v)
+		}
+	}
+}
+
+func TestAbandonLetsRetryRun(t *testing.T) {
+	store := NewMemory()
+	ctx := context.Background()
+
+	store.Begin(ctx, "k", "fp")
+	waiting := make(chan bool)
+	go func() {
+		_, claimed, _ := store.Begin(ctx, "k", "fp")
+		waiting <- claimed
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	store.Abandon("k") // say the handler failed with a 5xx
+	if !<-waiting {
+		t.Error("duplicate waiting on an abandoned claim did not get to run")
+	}
+}
+
+func TestWaitHonoursContext(t *testing.T) {
+	store := NewMemory()
+	store.Begin(context.Background(), "k", "fp")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := store.Begin(ctx, "k", "fp"); err == nil {
+		t.Error("gave up waiting without an error")
+	}
+}
+
+func TestScope(t *testing.T) {
+	mine := scope("198.51.100.7", "p1", "/submit", "key")
+	spoofed := scope("203.0.113.9", "p1", "/submit", "key")
+	if mine == spoofed {
+		t.Error("a different client naming the same player shares the key")
+	}
+	if scope("198.51.100.7", "", "/submit", "key") == scope("198.51.100.7", "", "/join", "key") {
+		t.Error("the same key on two routes shares a record")
+	}
+}
@@ -0,0 +1,143 @@
+package limit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"goyave.dev/goyave/v4"
+)
+
+// Requests per Per, allowed in a burst of up to Requests
+type Quota struct {
+	Requests int
+	Per      time.Duration
+}
+
+// token buckets by key, implemented in memory here and by anything shared
+// (Redis, say) that several instances should agree on
+type Store interface {
+	// take one token from key's bucket, or report how long until one is free
+	Take(key string, quota Quota, now time.Time) (ok bool, retryAfter time.Duration, err error)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	per    time.Duration // full again after this long idle
+}
+
+// Store for a single instance, idle buckets are swept once they'd be full again
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{buckets: make(map[string]*bucket)}
+}
+
+func (m *Memory) Take(key string, quota Quota, now time.Time) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.swept) > time.Minute {
+		m.sweep(now)
+	}
+
+	rate := float64(quota.Requests) / quota.Per.Seconds() // tokens per second
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(quota.Requests), last: now, per: quota.Per}
+		m.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(quota.Requests), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	return false, wait, nil
+}
+
+// a bucket idle for its whole period is full, forgetting it changes nothing
+func (m *Memory) sweep(now time.Time) {
+	for key, b := range m.buckets {
+		if now.Sub(b.last) > b.per {
+			delete(m.buckets, key)
+		}
+	}
+	m.swept = now
+}
+
+// rate limits the routes in quotas, keyed by route template
+type Limiter struct {
+	Store   Store
+	Quotas  map[string]Quota
+	Proxies int // trusted proxies in front of us, see ClientIP
+
+	// called for every rejected request, with the scope that ran out
+	Rejected func(route string, scope string)
+}
+
+// one bucket per client IP and route; a puuid in the body proves nothing
+// about who sent it, so there's no per-player bucket until players sign in
+func (l Limiter) Middleware(next goyave.Handler) goyave.Handler {
+	return func(response *goyave.Response, r *goyave.Request) {
+		if r.Route() == nil {
+			next(response, r)
+			return
+		}
+		route := r.Route().GetFullURI()
+		quota, ok := l.Quotas[route]
+		if !ok {
+			next(response, r)
+			return
+		}
+
+		ok, wait, err := l.Store.Take(route+"|ip:"+ClientIP(r, l.Proxies), quota, time.Now())
+		// a broken shared store shouldn't take the API down with it
+		if err == nil && !ok {
+			if l.Rejected != nil {
+				l.Rejected(route, "ip")
+			}
+			seconds := int(math.Ceil(wait.Seconds()))
+			response.Header().Set("Retry-After", strconv.Itoa(seconds))
+			response.String(http.StatusTooManyRequests, "Error: Too many requests, retry in "+strconv.Itoa(seconds)+"s.") // 429
+			return
+		}
+
+		next(response, r)
+	}
+}
+
+// each proxy appends the address it received from to X-Forwarded-For, so
+// with n trusted proxies the client is n entries from the end; anything
+// further left was sent by the client and can't be trusted
+func ClientIP(r *goyave.Request, proxies int) string {
+	if proxies > 0 {
+		var hops []string
+		for _, header := range r.Header().Values("X-Forwarded-For") {
+			for _, hop := range strings.Split(header, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+		if len(hops) >= proxies {
+			return hops[len(hops)-proxies]
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddress())
+	if err != nil {
+		return r.RemoteAddress()
+	}
+	return host
+}
@@ -0,0 +1,52 @@
+package limit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryTake(t *testing.T) {
+	store := NewMemory()
+	quota := Quota{Requests: 3, Per: time.Minute}
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < 3; i++ {
+		if ok, _, _ := store.Take("a", quota, now); !ok {
+			t.Fatalf("request %d of the burst refused", i+1)
+		}
+	}
+
+	ok, wait, err := store.Take("a", quota, now)
+	if ok || err != nil || wait != 20*time.Second {
+		t.Fatalf("fourth request: ok=%v wait=%s err=%v, want refused for 20s", ok, wait, err)
+	}
+
+	// other keys have their own bucket
+	if ok, _, _ := store.Take("b", quota, now); !ok {
+		t.Error("key b refused while only a ran out")
+	}
+
+	// one token back every 20s
+	if ok, _, _ := store.Take("a", quota, now.Add(20*time.Second)); !ok {
+		t.Error("refused after the bucket refilled a token")
+	}
+	if ok, _, _ := store.Take("a", quota, now.Add(20*time.Second)); ok {
+		t.Error("allowed a second request on one refilled token")
+	}
+}
+
+func TestMemorySweep(t *testing.T) {
+	store := NewMemory()
+	quota := Quota{Requests: 1, Per: time.Second}
+	now := time.Unix(1700000000, 0)
+
+	store.Take("a", quota, now)
+	store.Take("b", quota, now.Add(2*time.Minute)) // sweeps a, idle past its period
+
+	if _, ok := store.buckets["a"]; ok {
+		t.Error("idle bucket a was not swept")
+	}
+	if _, ok := store.buckets["b"]; !ok {
+		t.Error("fresh bucket b was swept")
+	}
+}
@@ -0,0 +1,154 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"riverboat/http/tracing"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"goyave.dev/goyave/v4"
+)
+
+// header a request ID is read from and echoed back in
+const Header = "X-Request-ID"
+
+// where Middleware leaves the request's logger in goyave.Request.Extra
+const extraKey = "logging.logger"
+
+// where NewContext leaves a logger
+type contextKey struct{}
+
+// ids picked out of route params and request data, under these log keys
+var ids = []string{"puuid", "suuid", "cuuid", "wuuid", "uuid"}
+
+// install a JSON or text slog handler at level as the default logger,
+// goyave's own loggers are routed through it too
+func Setup(w io.Writer, level string, format string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("log level: %w", err)
+	}
+
+	options := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	switch format {
+	case "json":
+		handler = slog.NewJSONHandler(w, options)
+	case "text":
+		handler = slog.NewTextHandler(w, options)
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	goyave.Logger = slog.NewLogLogger(handler, slog.LevelInfo)
+	goyave.AccessLogger = slog.NewLogLogger(handler, slog.LevelDebug)
+	goyave.ErrLogger = slog.NewLogLogger(handler, slog.LevelError)
+	return nil
+}
+
+// the logger Middleware attached to r, carrying its request ID and ids
+func From(r *goyave.Request) *slog.Logger {
+	if logger, ok := r.Extra[extraKey].(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// ctx carrying logger, for work a request starts that should still log
+// under its request ID once the handler has moved on
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// the logger NewContext put in ctx, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// give every request an ID, taken from the caller when it sends a sane one,
+// and log one line per request once it's done
+func Middleware(next goyave.Handler) goyave.Handler {
+	return func(response *goyave.Response, r *goyave.Request) {
+		start := time.Now()
+
+		id := r.Header().Get(Header)
+		if !valid(id) {
+			id = generate()
+		}
+		response.Header().Set(Header, id)
+
+		attrs := []any{"request_id", id}
+		if span := trace.SpanContextFromContext(tracing.Context(r)); span.IsValid() {
+			attrs = append(attrs, "trace_id", span.TraceID().String())
+		}
+		for _, key := range ids {
+			if value := lookup(r, key); value != "" {
+				attrs = append(attrs, key, value)
+			}
+		}
+		logger := slog.Default().With(attrs...)
+
+		if r.Extra == nil {
+			r.Extra = make(map[string]interface{})
+		}
+		r.Extra[extraKey] = logger
+
+		next(response, r)
+
+		route := "unmatched"
+		if r.Route() != nil {
+			route = r.Route().GetFullURI()
+		}
+		status := response.GetStatus()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+		logger.Log(r.Request().Context(), level, "request",
+			"method", r.Method(),
+			"route", route,
+			"status", status,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+	}
+}
+
+// route params win over body fields of the same name
+func lookup(r *goyave.Request, key string) string {
+	if value := r.Params[key]; value != "" {
+		return value
+	}
+	if value, ok := r.Data[key].(string); ok {
+		return value
+	}
+	return ""
+}
+
+// up to 128 printable characters, no spaces, so nothing odd ends up in logs
+func valid(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	return !strings.ContainsFunc(id, func(c rune) bool { return c <= ' ' || c > '~' })
+}
+
+func generate() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,180 @@
+package metrics
+
+import (
+	"net/http"
+	"riverboat/model"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"goyave.dev/goyave/v4"
+)
+
+var (
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "riverboat_http_requests_total",
+		Help: "HTTP requests by route template, method and status.",
+	}, []string{"route", "method", "status"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "riverboat_http_request_duration_seconds",
+		Help:    "HTTP request latency by route template, method and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	txDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "riverboat_neo4j_transaction_duration_seconds",
+		Help:    "Neo4j transaction latency by Env method and access mode, retries included.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "mode"})
+
+	txErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "riverboat_neo4j_transaction_errors_total",
+		Help: "Neo4j transactions that failed after retries, by Env method.",
+	}, []string{"method"})
+
+	txRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "riverboat_neo4j_transaction_retries_total",
+		Help: "Times the driver re-ran a transaction function, by Env method.",
+	}, []string{"method"})
+
+	limited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "riverboat_rate_limited_total",
+		Help: "Requests rejected with 429, by route template and the bucket that ran out.",
+	}, []string{"route", "scope"})
+
+	payoutDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "riverboat_payouts_duration_seconds",
+		Help:    "calc payout computation time by space kind and player and field count buckets.",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10),
+	}, []string{"kind", "players", "fields"})
+)
+
+func init() {
+	prometheus.MustRegister(requests, requestDuration, txDuration, txErrors, txRetries, limited, payoutDuration, totals)
+}
+
+// the default registry in the Prometheus text format
+var scrape = promhttp.Handler()
+
+// serves the default registry in the Prometheus text format
+func Handler(response *goyave.Response, r *goyave.Request) {
+	scrape.ServeHTTP(response, r.Request())
+}
+
+// counts and times every request under its route template, not the raw
+// path, so uuids don't blow up the label set
+func Middleware(next goyave.Handler) goyave.Handler {
+	return func(response *goyave.Response, r *goyave.Request) {
+		start := time.Now()
+		next(response, r)
+
+		route := "unmatched"
+		if r.Route() != nil {
+			route = r.Route().GetFullURI()
+		}
+		status := response.GetStatus()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		labels := prometheus.Labels{"route": route, "method": r.Method(), "status": strconv.Itoa(status)}
+		requests.With(labels).Inc()
+		requestDuration.With(labels).Observe(time.Since(start).Seconds())
+	}
+}
+
+// record one transaction function, attempts above 1 are driver retries
+func Transaction(method string, mode string, attempts int, elapsed time.Duration, err error) {
+	txDuration.WithLabelValues(method, mode).Observe(elapsed.Seconds())
+	if attempts > 1 {
+		txRetries.WithLabelValues(method).Add(float64(attempts - 1))
+	}
+	if err != nil {
+		txErrors.WithLabelValues(method).Inc()
+	}
+}
+
+// record a request turned away by the rate limiter, matches limit.Limiter.Rejected
+func Limited(route string, scope string) {
+	limited.WithLabelValues(route, scope).Inc()
+}
+
+// record one payout computation, matches the calc.Observe signature
+func Payouts(kind string, players int, fields int, elapsed time.Duration) {
+	if kind == "" {
+		kind = model.Categorical
+	}
+	payoutDuration.WithLabelValues(kind, size(players), size(fields)).Observe(elapsed.Seconds())
+}
+
+// bucket a count into a handful of label values
+func size(n int) string {
+	switch {
+	case n <= 5:
+		return "1-5"
+	case n <= 20:
+		return "6-20"
+	case n <= 100:
+		return "21-100"
+	default:
+		return "100+"
+	}
+}
+
+// gauges read from the graph when scraped, at most once per TTL;
+// registered once with the rest and silent until Business gives a source
+type business struct {
+	source func() (model.Totals, error)
+	ttl    time.Duration
+
+	mu      sync.Mutex
+	last    model.Totals
+	fetched time.Time
+
+	openSpaces    *prometheus.Desc
+	activePlayers *prometheus.Desc
+	escrow        *prometheus.Desc
+}
+
+var totals = &business{
+	ttl:           30 * time.Second,
+	openSpaces:    prometheus.NewDesc("riverboat_open_spaces", "Spaces not yet resolved or voided.", nil, nil),
+	activePlayers: prometheus.NewDesc("riverboat_active_players", "Players with a current model in an open space.", nil, nil),
+	escrow:        prometheus.NewDesc("riverboat_escrow_total", "Money held in unsettled escrow.", nil, nil),
+}
+
+// read the business gauges from source, replacing any earlier one
+func Business(source func() (model.Totals, error)) {
+	totals.mu.Lock()
+	defer totals.mu.Unlock()
+	totals.source, totals.last, totals.fetched = source, model.Totals{}, time.Time{}
+}
+
+func (b *business) Describe(ch chan<- *prometheus.Desc) {
+	ch <- b.openSpaces
+	ch <- b.activePlayers
+	ch <- b.escrow
+}
+
+// serves the last good totals if a refresh fails
+func (b *business) Collect(ch chan<- prometheus.Metric) {
+	b.mu.Lock()
+	if b.source == nil {
+		b.mu.Unlock()
+		return
+	}
+	if time.Since(b.fetched) > b.ttl {
+		if totals, err := b.source(); err == nil {
+			b.last, b.fetched = totals, time.Now()
+		}
+	}
+	last := b.last
+	b.mu.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(b.openSpaces, prometheus.GaugeValue, float64(last.OpenSpaces))
+	ch <- prometheus.MustNewConstMetric(b.activePlayers, prometheus.GaugeValue, float64(last.ActivePlayers))
+	ch <- prometheus.MustNewConstMetric(b.escrow, prometheus.GaugeValue, last.Escrow)
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"riverboat/model"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// the value of a gauge on the default registry, false if it's not there
+func gauge(t *testing.T, name string) (float64, bool) {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() == name {
+			return family.GetMetric()[0].GetGauge().GetValue(), true
+		}
+	}
+	return 0, false
+}
+
+func TestBusinessTwice(t *testing.T) {
+	if _, ok := gauge(t, "riverboat_open_spaces"); ok {
+		t.Error("gauges reported before there is a source")
+	}
+
+	Business(func() (model.Totals, error) { return model.Totals{OpenSpaces: 3}, nil })
+	// a second call, as a test or a restarted server would make, replaces the source
+	Business(func() (model.Totals, error) { return model.Totals{OpenSpaces: 5, Escrow: 12.5}, nil })
+
+	if open, _ := gauge(t, "riverboat_open_spaces"); open != 5 {
+		t.Errorf("open spaces %g, want 5 from the latest source", open)
+	}
+	if escrow, _ := gauge(t, "riverboat_escrow_total"); escrow != 12.5 {
+		t.Errorf("escrow %g, want 12.5", escrow)
+	}
+}
+
+func TestBusinessKeepsLastGood(t *testing.T) {
+	calls := 0
+	Business(func() (model.Totals, error) {
+		calls++
+		if calls > 1 {
+			return model.Totals{}, errors.New("aura unreachable")
+		}
+		return model.Totals{ActivePlayers: 4}, nil
+	})
+
+	gauge(t, "riverboat_active_players")
+	totals.mu.Lock()
+	totals.fetched = totals.fetched.Add(-time.Hour) // past the TTL
+	totals.mu.Unlock()
+
+	if active, _ := gauge(t, "riverboat_active_players"); active != 4 || calls != 2 {
+		t.Errorf("active players %g after %d reads, want the last good 4 after a failed refresh", active, calls)
+	}
+}
+
+func TestSize(t *testing.T) {
+	for n, want := range map[int]string{1: "1-5", 5: "1-5", 6: "6-20", 100: "21-100", 101: "100+"} {
+		if got := size(n); got != want {
+			t.Errorf("size(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestScrape(t *testing.T) {
+	Transaction("getSpace", "read", 2, 10*time.Millisecond, nil)
+	Payouts("categorical", 3, 2, time.Millisecond)
+	Business(func() (model.Totals, error) { return model.Totals{OpenSpaces: 1}, nil })
+
+	server := httptest.NewServer(scrape)
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("scrape answered %d", resp.StatusCode)
+	}
+	for _, series := range []string{
+		`riverboat_neo4j_transaction_duration_seconds_count{method="getSpace",mode="read"} 1`,
+		`riverboat_neo4j_transaction_retries_total{method="getSpace"} 1`,
+		`riverboat_payouts_duration_seconds_count{fields="1-5",kind="categorical",players="1-5"} 1`,
+		`riverboat_open_spaces 1`,
+	} {
+		if !strings.Contains(string(body), series) {
+			t.Errorf("scrape is missing %s", series)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package route
+
+import (
+	"riverboat/http/bot"
+	"riverboat/http/hub"
+	"riverboat/model"
+)
+
+// submits a model for every bot in a circle as soon as a space opens there
+type Bots struct {
+	DB      Controls
+	Hub     *hub.Hub
+	Workers *Workers
+}
+
+// hub listener for SpaceCreated on the circle's topic
+func (b Bots) Open(event hub.Event) {
+	if event.Kind != hub.SpaceCreated {
+		return
+	}
+
+	space, ok := event.Data.(model.Space)
+	if !ok {
+		return
+	}
+
+	b.Workers.Spawn(func() {
+		bots, err := b.DB.listBots(event.Topic)
+		if err != nil {
+			logger(b.DB).Error("listing bots", "cuuid", event.Topic, "err", err)
+			return
+		}
+		for _, player := range bots {
+			b.play(player, space)
+		}
+	})
+}
+
+// let one bot submit to one space, seeing everything submitted before it;
+// logs under the request DB came from, when a request started it
+func (b Bots) play(player model.Bot, space model.Space) {
+	logger := logger(b.DB).With("bot", player.Name, "puuid", player.Uuid, "suuid", space.Uuid)
+
+	strategy, err := bot.Lookup(player.Strategy, player.Target)
+	if err != nil {
+		logger.Error("unknown strategy", "err", err)
+		return
+	}
+
+	models, err := b.DB.mapModels(space.Uuid)
+	if err != nil {
+		logger.Error("loading models", "err", err)
+		return
+	}
+	delete(models, player.Name)
+
+	certs := strategy.Model(space, models)
+	if err := model.CheckModel(space, certs); err != nil {
+		logger.Error("invalid model", "err", err)
+		return
+	}
+
+	version, err := b.DB.submitModel(player.Uuid, space.Uuid, certs, nil)
+	if err != nil {
+		logger.Error("submitting model", "err", err)
+		return
+	}
+	b.Hub.Publish(space.Uuid, hub.ModelSubmitted, map[string]interface{}{"puuid": player.Uuid, "model": certs, "version": version})
+}
+
+// flatten a space tree back into the open spaces in it
+func openSpaces(spaces []model.Space) []model.Space {
+	var open []model.Space
+	for _, space := range spaces {
+		if space.Resolved == 0 {
+			open = append(open, space)
+		}
+		open = append(open, openSpaces(space.Children)...)
+	}
+	return open
+}
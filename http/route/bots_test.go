@@ -0,0 +1,73 @@
+package route
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"riverboat/http/bot"
+	"riverboat/http/hub"
+	"riverboat/http/logging"
+	"riverboat/model"
+	"strings"
+	"testing"
+)
+
+// Controls carrying a request context and nothing else
+type requested struct {
+	Controls
+	ctx context.Context
+}
+
+func (db requested) requestContext() context.Context {
+	return db.ctx
+}
+
+func TestBotLogsUnderRequest(t *testing.T) {
+	var out bytes.Buffer
+	request := slog.New(slog.NewTextHandler(&out, nil)).With("request_id", "r-42")
+	db := requested{ctx: logging.NewContext(context.Background(), request)}
+
+	Bots{DB: db}.play(model.Bot{Name: "b", Uuid: "p1", Strategy: "no such strategy"}, model.Space{Uuid: "s1"})
+
+	if line := out.String(); !strings.Contains(line, "request_id=r-42") || !strings.Contains(line, "puuid=p1") {
+		t.Errorf("logged %q, want the request's id and the bot's puuid", line)
+	}
+}
+
+func TestLoggerOutsideRequest(t *testing.T) {
+	if logger(requested{ctx: context.Background()}) != slog.Default() {
+		t.Error("work outside a request did not log through the default logger")
+	}
+}
+
+// Controls a bot can submit through, each submission one version later
+type submitter struct {
+	requested
+	version int64
+}
+
+func (db *submitter) mapModels(suuid string) (map[string]map[string]float64, error) {
+	return map[string]map[string]float64{}, nil
+}
+
+func (db *submitter) submitModel(puuid string, suuid string, json map[string]float64, expected []int64) (int64, error) {
+	db.version++
+	return db.version, nil
+}
+
+func TestBotPublishesVersion(t *testing.T) {
+	events := hub.New(8)
+	var submitted []hub.Event
+	events.Listen(func(event hub.Event) { submitted = append(submitted, event) })
+
+	db := &submitter{requested: requested{ctx: context.Background()}, version: 2}
+	space := model.Space{Uuid: "s1", Kind: model.Categorical, Fields: []string{"x", "y"}}
+	Bots{DB: db, Hub: events}.play(model.Bot{Name: "b", Uuid: "p1", Strategy: bot.Uniform}, space)
+
+	if len(submitted) != 1 || submitted[0].Kind != hub.ModelSubmitted {
+		t.Fatalf("published %+v", submitted)
+	}
+	if data := submitted[0].Data.(map[string]interface{}); data["version"] != int64(3) || data["puuid"] != "p1" {
+		t.Errorf("published %v, want version 3 like a player's submission", data)
+	}
+}
@@ -0,0 +1,137 @@
+package route
+
+import (
+	"encoding/json"
+	"net/http"
+	"riverboat/http/hub"
+	"riverboat/http/webhook"
+	"time"
+)
+
+const (
+	dispatcherName = "webhooks" // as reported by readiness
+	deliveryBatch  = 20
+	deliveryLease  = time.Minute
+	eventBuffer    = 256 // events waiting to be queued before Enqueue spills
+)
+
+// moves hub events into the persistent webhook queue and drains it
+type Dispatcher struct {
+	DB       Controls
+	Client   *http.Client
+	Interval time.Duration
+	Workers  *Workers
+	events   chan hub.Event
+}
+
+func NewDispatcher(db Controls, workers *Workers) Dispatcher {
+	return Dispatcher{
+		DB:       db,
+		Client:   webhook.NewClient(10 * time.Second),
+		Interval: 5 * time.Second,
+		Workers:  workers,
+		events:   make(chan hub.Event, eventBuffer),
+	}
+}
+
+// run the delivery loop under Workers until it drains
+func (d Dispatcher) Start() {
+	d.Workers.Go(dispatcherName, d.Run)
+}
+
+// hub listener: hand the event to Run without blocking the publisher,
+// spilling to a one-off worker when Run has fallen behind; once draining
+// has begun neither would queue it, so it is logged as dropped
+func (d Dispatcher) Enqueue(event hub.Event) {
+	if d.Workers.draining() {
+		logger(d.DB).Warn("webhook event dropped, shutting down", "topic", event.Topic, "event", event.Kind)
+		return
+	}
+
+	select {
+	case d.events <- event:
+	default:
+		d.Workers.Spawn(func() { d.store(event) })
+	}
+}
+
+// queue the event for every webhook on its circle
+func (d Dispatcher) store(event hub.Event) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"event": event.Kind,
+		"topic": event.Topic,
+		"data":  event.Data,
+		"time":  time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		logger(d.DB).Error("webhook payload", "topic", event.Topic, "event", event.Kind, "err", err)
+		return
+	}
+
+	if err := d.DB.enqueueDeliveries(event.Topic, event.Kind, string(payload)); err != nil {
+		logger(d.DB).Error("webhook enqueue", "topic", event.Topic, "event", event.Kind, "err", err)
+	}
+}
+
+// queue events as they arrive and deliver due jobs every Interval until
+// stop is closed, then queue whatever events are still buffered
+func (d Dispatcher) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			for {
+				select {
+				case event := <-d.events:
+					d.store(event)
+				default:
+					return
+				}
+			}
+		case event := <-d.events:
+			d.store(event)
+		case <-ticker.C:
+			d.Workers.Beat(dispatcherName)
+			d.flush(stop)
+		}
+	}
+}
+
+// stops between jobs once stop is closed, anything leased but unsent
+// is picked up again when its lease runs out
+func (d Dispatcher) flush(stop <-chan struct{}) {
+	jobs, err := d.DB.dueDeliveries(deliveryBatch, deliveryLease)
+	if err != nil {
+		logger(d.DB).Error("webhook queue", "err", err)
+		return
+	}
+
+	for _, job := range jobs {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		d.Workers.Beat(dispatcherName)
+
+		code, err := webhook.Send(d.Client, job)
+		attempts := job.Attempts + 1
+
+		status, failure, next := "delivered", "", time.Now()
+		if err != nil {
+			failure = err.Error()
+			if attempts >= webhook.MaxAttempts {
+				status = "dead"
+			} else {
+				status = "pending"
+				next = next.Add(webhook.Backoff(attempts))
+			}
+		}
+
+		if err := d.DB.recordDelivery(job.Delivery, status, code, failure, next); err != nil {
+			logger(d.DB).Error("webhook record", "delivery", job.Delivery, "status", status, "err", err)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package route
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"riverboat/http/hub"
+	"riverboat/http/logging"
+	"riverboat/http/webhook"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Controls backed by an in-memory delivery queue, every other method
+// panics through the nil embedded interface
+type queue struct {
+	Controls
+	mu       sync.Mutex
+	jobs     []webhook.Job
+	queued   []string
+	recorded map[string]string
+	next     map[string]time.Time
+	ctx      context.Context
+}
+
+func (q *queue) requestContext() context.Context {
+	if q.ctx == nil {
+		return context.Background()
+	}
+	return q.ctx
+}
+
+func (q *queue) enqueueDeliveries(topic string, event string, payload string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.queued = append(q.queued, topic+" "+event)
+	return nil
+}
+
+func (q *queue) dueDeliveries(limit int, lease time.Duration) ([]webhook.Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs := q.jobs
+	q.jobs = nil
+	return jobs, nil
+}
+
+func (q *queue) recordDelivery(duuid string, status string, code int, failure string, next time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.recorded[duuid] = status
+	q.next[duuid] = next
+	return nil
+}
+
+func TestDispatcherFlush(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ok.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer down.Close()
+
+	db := &queue{
+		jobs: []webhook.Job{
+			{Delivery: "sent", URL: ok.URL, Payload: "{}"},
+			{Delivery: "retry", URL: down.URL, Payload: "{}", Attempts: 1},
+			{Delivery: "dead", URL: down.URL, Payload: "{}", Attempts: webhook.MaxAttempts - 1},
+		},
+		recorded: make(map[string]string),
+		next:     make(map[string]time.Time),
+	}
+	d := Dispatcher{DB: db, Client: ok.Client()}
+
+	before := time.Now()
+	d.flush(make(chan struct{}))
+
+	want := map[string]string{"sent": "delivered", "retry": "pending", "dead": "dead"}
+	for delivery, status := range want {
+		if db.recorded[delivery] != status {
+			t.Errorf("%s: got %q, want %q", delivery, db.recorded[delivery], status)
+		}
+	}
+	if wait := db.next["retry"].Sub(before); wait < webhook.Backoff(2) {
+		t.Errorf("retry scheduled after %s, want at least %s", wait, webhook.Backoff(2))
+	}
+}
+
+func TestDispatcherEnqueue(t *testing.T) {
+	db := &queue{}
+	workers := NewWorkers()
+	d := NewDispatcher(db, workers)
+	d.Interval = time.Hour
+	d.Start()
+
+	// more events than the buffer holds, none may block the publisher
+	for i := 0; i < eventBuffer*2; i++ {
+		d.Enqueue(hub.Event{Topic: "c1", Kind: hub.PlayerJoined})
+	}
+	if err := workers.Drain(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(db.queued) != eventBuffer*2 {
+		t.Errorf("queued %d events, want %d", len(db.queued), eventBuffer*2)
+	}
+}
+
+func TestDispatcherDropsAfterDrain(t *testing.T) {
+	var out bytes.Buffer
+	db := &queue{ctx: logging.NewContext(context.Background(), slog.New(slog.NewTextHandler(&out, nil)))}
+	workers := NewWorkers()
+	d := NewDispatcher(db, workers)
+	d.Interval = time.Hour
+	d.Start()
+
+	if err := workers.Drain(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	d.Enqueue(hub.Event{Topic: "c1", Kind: hub.PlayerJoined})
+
+	if len(db.queued) != 0 || len(d.events) != 0 {
+		t.Errorf("an event enqueued after Run exited was kept: %v", db.queued)
+	}
+	if line := out.String(); !strings.Contains(line, "dropped") || !strings.Contains(line, "topic=c1") {
+		t.Errorf("logged %q, want the dropped event", line)
+	}
+}
@@ -0,0 +1,137 @@
+package route
+
+import (
+	"fmt"
+	"math"
+	"riverboat/model"
+	"sort"
+	"strings"
+)
+
+// tag a Neo4j property with its type so ints and floats survive JSON
+func typed(value interface{}) (model.Prop, error) {
+	switch v := value.(type) {
+	case int64:
+		return model.Prop{Type: "int", Value: v}, nil
+	case float64:
+		return model.Prop{Type: "float", Value: v}, nil
+	case string:
+		return model.Prop{Type: "string", Value: v}, nil
+	case bool:
+		return model.Prop{Type: "bool", Value: v}, nil
+	case []interface{}:
+		kind := "list:string"
+		if len(v) > 0 {
+			first, err := typed(v[0])
+			if err != nil {
+				return model.Prop{}, err
+			}
+			kind = "list:" + first.Type
+		}
+		return model.Prop{Type: kind, Value: v}, nil
+	default:
+		return model.Prop{}, fmt.Errorf("cannot export property of type %T", value)
+	}
+}
+
+// back to the Go value the driver stores as the tagged type; a value that
+// doesn't fit its tag, say from a hand-edited file, is an error
+func untyped(prop model.Prop) (interface{}, error) {
+	switch prop.Type {
+	case "int", "float", "string", "bool":
+		return scalar(prop.Type, prop.Value)
+	case "list:string":
+		return list(prop, []string{})
+	case "list:float":
+		return list(prop, []float64{})
+	case "list:int":
+		return list(prop, []int64{})
+	default:
+		return nil, fmt.Errorf("cannot import property of type %s", prop.Type)
+	}
+}
+
+// one value as kind, from what encoding/json decoded it to
+func scalar(kind string, value interface{}) (interface{}, error) {
+	var ok bool
+	switch kind {
+	case "int":
+		var f float64
+		if f, ok = value.(float64); ok && f == math.Trunc(f) {
+			return int64(f), nil
+		}
+	case "float":
+		var f float64
+		f, ok = value.(float64)
+		return f, checked(kind, value, ok)
+	case "string":
+		var s string
+		s, ok = value.(string)
+		return s, checked(kind, value, ok)
+	case "bool":
+		var b bool
+		b, ok = value.(bool)
+		return b, checked(kind, value, ok)
+	}
+	return nil, checked(kind, value, false)
+}
+
+// every item of a list property appended to into, typed as its tag says
+func list[T any](prop model.Prop, into []T) (interface{}, error) {
+	items, ok := prop.Value.([]interface{})
+	if !ok {
+		return nil, checked(prop.Type, prop.Value, false)
+	}
+	kind := strings.TrimPrefix(prop.Type, "list:")
+	for i, item := range items {
+		value, err := scalar(kind, item)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		into = append(into, value.(T))
+	}
+	return into, nil
+}
+
+func checked(kind string, value interface{}, ok bool) error {
+	if ok {
+		return nil
+	}
+	return fmt.Errorf("%v is not a %s", value, kind)
+}
+
+func typedProps(props map[string]interface{}) (map[string]model.Prop, error) {
+	tagged := make(map[string]model.Prop)
+	for key, value := range props {
+		prop, err := typed(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		tagged[key] = prop
+	}
+	return tagged, nil
+}
+
+func untypedProps(props map[string]model.Prop) (map[string]interface{}, error) {
+	plain := make(map[string]interface{})
+	for key, prop := range props {
+		value, err := untyped(prop)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		plain[key] = value
+	}
+	return plain, nil
+}
+
+// backtick-quoted label or type list usable in a Cypher pattern
+func quoteNames(names []string) string {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+
+	quoted := ""
+	for _, name := range sorted {
+		quoted += ":`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return quoted
+}
@@ -0,0 +1,76 @@
+package route
+
+import (
+	"encoding/json"
+	"reflect"
+	"riverboat/model"
+	"strings"
+	"testing"
+)
+
+func TestUntyped(t *testing.T) {
+	cases := []struct {
+		prop string
+		want interface{}
+		ok   bool
+	}{
+		{`{"type": "int", "value": 3}`, int64(3), true},
+		{`{"type": "float", "value": 2.5}`, 2.5, true},
+		{`{"type": "string", "value": "s"}`, "s", true},
+		{`{"type": "bool", "value": true}`, true, true},
+		{`{"type": "list:string", "value": ["a", "b"]}`, []string{"a", "b"}, true},
+		{`{"type": "list:int", "value": [1, 2]}`, []int64{1, 2}, true},
+		{`{"type": "list:float", "value": []}`, []float64{}, true},
+		{`{"type": "int", "value": "3"}`, nil, false},
+		{`{"type": "int", "value": 1.5}`, nil, false},
+		{`{"type": "float", "value": null}`, nil, false},
+		{`{"type": "bool", "value": "yes"}`, nil, false},
+		{`{"type": "list:string", "value": "a"}`, nil, false},
+		{`{"type": "list:int", "value": [1, "2"]}`, nil, false},
+		{`{"type": "date", "value": "2024-01-01"}`, nil, false},
+	}
+
+	for _, c := range cases {
+		var prop model.Prop
+		if err := json.Unmarshal([]byte(c.prop), &prop); err != nil {
+			t.Fatal(err)
+		}
+		got, err := untyped(prop)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: err = %v, want ok=%v", c.prop, err, c.ok)
+			continue
+		}
+		if c.ok && !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %#v, want %#v", c.prop, got, c.want)
+		}
+	}
+}
+
+// a corrupt file is turned away naming what's wrong, before the database
+// is touched; Env{} has no driver and would panic if it were
+func TestLoadCorruptDump(t *testing.T) {
+	var dump model.Dump
+	err := json.Unmarshal([]byte(`{
+		"nodes": [
+			{"id": "1", "labels": ["Player"], "props": {"name": {"type": "string", "value": "ann"}}},
+			{"id": "2", "labels": ["Player"], "props": {"money": {"type": "float", "value": "lots"}}}
+		],
+		"relationships": []
+	}`), &dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Env{}.load(dump)
+	if err == nil || !strings.Contains(err.Error(), "node 2") || !strings.Contains(err.Error(), "money") {
+		t.Errorf("got %v, want an error naming node 2 and money", err)
+	}
+
+	dump.Nodes = dump.Nodes[:1]
+	dump.Relationships = []model.DumpRelationship{{Start: "1", End: "1", Type: "SETS",
+		Props: map[string]model.Prop{"at": {Type: "int", Value: true}}}}
+	_, err = Env{}.load(dump)
+	if err == nil || !strings.Contains(err.Error(), "SETS") || !strings.Contains(err.Error(), "at") {
+		t.Errorf("got %v, want an error naming the SETS relationship and at", err)
+	}
+}
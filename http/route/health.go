@@ -0,0 +1,93 @@
+package route
+
+import (
+	"fmt"
+	"net/http"
+	"riverboat/model"
+	"sync"
+	"time"
+
+	"goyave.dev/goyave/v4"
+)
+
+// caches readiness for TTL so load balancer probes don't each hit Aura
+type Readiness struct {
+	DB      Controls
+	Workers *Workers
+	TTL     time.Duration
+	Stale   time.Duration // how long a worker loop may go without a beat
+
+	mu   sync.Mutex
+	last model.Readiness
+}
+
+func NewReadiness(db Controls, workers *Workers) *Readiness {
+	return &Readiness{
+		DB:      db,
+		Workers: workers,
+		TTL:     5 * time.Second,
+		Stale:   time.Minute,
+	}
+}
+
+// the cached result, or a fresh one once it's older than TTL
+// concurrent probes wait for the one check in progress
+func (rd *Readiness) Check() model.Readiness {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+
+	if time.Since(rd.last.Checked) < rd.TTL {
+		return rd.last
+	}
+
+	checks := map[string]model.Check{
+		"neo4j":   {OK: true},
+		"schema":  {OK: true},
+		"workers": {OK: true},
+	}
+
+	if err := rd.DB.getStatus(); err != nil {
+		checks["neo4j"] = model.Check{Detail: err.Error()}
+		checks["schema"] = model.Check{Detail: "neo4j unreachable"}
+	} else if version, err := rd.DB.schemaVersion(); err != nil {
+		checks["schema"] = model.Check{Detail: err.Error()}
+	} else if version != len(migrations) {
+		checks["schema"] = model.Check{Detail: fmt.Sprintf("at version %d, want %d; run riverboat migrate", version, len(migrations))}
+	}
+
+	if err := rd.Workers.Check(rd.Stale); err != nil {
+		checks["workers"] = model.Check{Detail: err.Error()}
+	}
+
+	ready := true
+	for _, check := range checks {
+		ready = ready && check.OK
+	}
+
+	rd.last = model.Readiness{Ready: ready, Checks: checks, Checked: time.Now()}
+	return rd.last
+}
+
+// process is up and serving, nothing else is checked
+func (h Handler) Healthz(response *goyave.Response, r *goyave.Request) {
+	response.String(http.StatusOK, "ok")
+}
+
+// 503 with the failing checks until Neo4j, the schema and the workers are all fine
+func (h Handler) Readyz(response *goyave.Response, r *goyave.Request) {
+	if h.Ready == nil {
+		response.String(http.StatusNotImplemented, "Error: Readiness unsupported.") // 501
+		return
+	}
+
+	readiness := h.Ready.Check()
+	response.JSON(readyStatus(readiness), readiness)
+}
+
+// 503 for a load balancer to route around unless every check passed
+func readyStatus(readiness model.Readiness) int {
+	if readiness.Ready {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable // 503
+}
@@ -0,0 +1,97 @@
+package route
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Controls answering readiness probes, counting how often Neo4j was asked
+type probed struct {
+	Controls
+	down    error
+	version int
+	broken  error // reading the schema version
+	calls   int
+}
+
+func (p *probed) getStatus() error {
+	p.calls++
+	return p.down
+}
+
+func (p *probed) schemaVersion() (int, error) {
+	return p.version, p.broken
+}
+
+func TestReadinessCaches(t *testing.T) {
+	db := &probed{version: len(migrations)}
+	rd := NewReadiness(db, NewWorkers())
+	rd.TTL = time.Hour
+
+	if !rd.Check().Ready {
+		t.Fatalf("not ready: %+v", rd.Check())
+	}
+
+	db.down = errors.New("connection refused")
+	if cached := rd.Check(); !cached.Ready || db.calls != 1 {
+		t.Errorf("asked neo4j %d times within the TTL, got %+v", db.calls, cached)
+	}
+
+	rd.TTL = 0
+	if fresh := rd.Check(); fresh.Ready || db.calls != 2 {
+		t.Errorf("asked neo4j %d times once the TTL passed, got %+v", db.calls, fresh)
+	}
+}
+
+func TestReadinessFailures(t *testing.T) {
+	cases := []struct {
+		name    string
+		db      probed
+		workers func(*Workers)
+		failing []string
+	}{
+		{"ready", probed{version: len(migrations)}, nil, nil},
+		{"neo4j down", probed{down: errors.New("connection refused")}, nil, []string{"neo4j", "schema"}},
+		{"schema unread", probed{broken: errors.New("timeout")}, nil, []string{"schema"}},
+		{"schema behind", probed{version: len(migrations) - 1}, nil, []string{"schema"}},
+		{"stale worker", probed{version: len(migrations)}, func(w *Workers) {
+			w.beats[dispatcherName] = time.Now().Add(-time.Hour)
+		}, []string{"workers"}},
+		{"draining", probed{version: len(migrations)}, func(w *Workers) {
+			w.Drain(time.Second)
+		}, []string{"workers"}},
+	}
+	for _, c := range cases {
+		workers := NewWorkers()
+		if c.workers != nil {
+			c.workers(workers)
+		}
+		readiness := NewReadiness(&c.db, workers).Check()
+
+		var failing []string
+		for _, name := range []string{"neo4j", "schema", "workers"} {
+			if check, ok := readiness.Checks[name]; !ok {
+				t.Errorf("%s: no %s check", c.name, name)
+			} else if !check.OK {
+				if check.Detail == "" {
+					t.Errorf("%s: %s failed without saying why", c.name, name)
+				}
+				failing = append(failing, name)
+			}
+		}
+		if !reflect.DeepEqual(failing, c.failing) || readiness.Ready != (failing == nil) {
+			t.Errorf("%s: failing %v, want %v", c.name, failing, c.failing)
+		}
+
+		want := http.StatusServiceUnavailable
+		if readiness.Ready {
+			want = http.StatusOK
+		}
+		if status := readyStatus(readiness); status != want {
+			t.Errorf("%s: probe got %d, want %d", c.name, status, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package route
+
+import (
+	"context"
+	"riverboat/http/metrics"
+	"riverboat/http/tracing"
+	"riverboat/model"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// copy of env whose sessions trace under ctx
+func (env Env) withContext(ctx context.Context) Controls {
+	env.ctx = ctx
+	return env
+}
+
+func (env Env) requestContext() context.Context {
+	if env.ctx == nil {
+		return context.Background()
+	}
+	return env.ctx
+}
+
+// session that reports each transaction under the Env method that opened it,
+// as a metric and as a span named after the method, never its parameters
+type measured struct {
+	neo4j.Session
+	method string
+	ctx    context.Context
+}
+
+func (env Env) session(method string, config neo4j.SessionConfig) neo4j.Session {
+	return measured{Session: env.Driver.NewSession(config), method: method, ctx: env.requestContext()}
+}
+
+func (m measured) ReadTransaction(work neo4j.TransactionWork, configurers ...func(*neo4j.TransactionConfig)) (interface{}, error) {
+	return m.measure("read", m.Session.ReadTransaction, work, configurers)
+}
+
+func (m measured) WriteTransaction(work neo4j.TransactionWork, configurers ...func(*neo4j.TransactionConfig)) (interface{}, error) {
+	return m.measure("write", m.Session.WriteTransaction, work, configurers)
+}
+
+// auto-commit statements are timed until their result is available
+func (m measured) Run(cypher string, params map[string]interface{}, configurers ...func(*neo4j.TransactionConfig)) (neo4j.Result, error) {
+	span := m.start("auto")
+	defer span.End()
+
+	start := time.Now()
+	result, err := m.Session.Run(cypher, params, configurers...)
+	metrics.Transaction(m.method, "auto", 1, time.Since(start), err)
+	finish(span, 1, err)
+	return result, err
+}
+
+// the driver calls work again on transient errors, so counting calls gives retries
+func (m measured) measure(
+	mode string,
+	run func(neo4j.TransactionWork, ...func(*neo4j.TransactionConfig)) (interface{}, error),
+	work neo4j.TransactionWork,
+	configurers []func(*neo4j.TransactionConfig)) (interface{}, error) {
+
+	span := m.start(mode)
+	defer span.End()
+
+	attempts, rows := 0, 0
+	start := time.Now()
+	result, err := run(func(tx neo4j.Transaction) (interface{}, error) {
+		attempts++
+		rows = 0 // only the attempt that commits counts
+		return work(counting{Transaction: tx, rows: &rows})
+	}, configurers...)
+	metrics.Transaction(m.method, mode, attempts, time.Since(start), err)
+	span.SetAttributes(attribute.Int("db.neo4j.rows", rows))
+	finish(span, attempts, err)
+	return result, err
+}
+
+func (m measured) start(mode string) trace.Span {
+	_, span := tracing.Start(m.ctx, "neo4j "+m.method,
+		attribute.String("db.system", "neo4j"),
+		attribute.String("db.operation", m.method),
+		attribute.String("db.neo4j.access_mode", mode),
+	)
+	return span
+}
+
+func finish(span trace.Span, attempts int, err error) {
+	span.SetAttributes(attribute.Int("db.neo4j.attempts", attempts))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "transaction failed")
+	}
+}
+
+// span around one calc phase, parented to the request db traces under
+func calcSpan(db Controls, phase string, space model.Space, players int) trace.Span {
+	kind := space.Kind
+	if kind == "" {
+		kind = model.Categorical
+	}
+	_, span := tracing.Start(db.requestContext(), "calc "+phase,
+		attribute.String("space.kind", kind),
+		attribute.Int("calc.players", players),
+		attribute.Int("calc.fields", len(space.Fields)),
+	)
+	return span
+}
+
+// transaction whose results count the records read from them
+type counting struct {
+	neo4j.Transaction
+	rows *int
+}
+
+func (c counting) Run(cypher string, params map[string]interface{}) (neo4j.Result, error) {
+	result, err := c.Transaction.Run(cypher, params)
+	if err != nil {
+		return nil, err
+	}
+	return counted{Result: result, rows: c.rows}, nil
+}
+
+type counted struct {
+	neo4j.Result
+	rows *int
+}
+
+func (c counted) Next() bool {
+	next := c.Result.Next()
+	if next {
+		*c.rows++
+	}
+	return next
+}
+
+func (c counted) NextRecord(record **neo4j.Record) bool {
+	next := c.Result.NextRecord(record)
+	if next {
+		*c.rows++
+	}
+	return next
+}
+
+func (c counted) Single() (*neo4j.Record, error) {
+	record, err := c.Result.Single()
+	if err == nil {
+		*c.rows++
+	}
+	return record, err
+}
+
+func (c counted) Collect() ([]*neo4j.Record, error) {
+	records, err := c.Result.Collect()
+	*c.rows += len(records)
+	return records, err
+}
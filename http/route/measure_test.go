@@ -0,0 +1,92 @@
+package route
+
+import (
+	"context"
+	"riverboat/model"
+	"testing"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// session whose transactions hand work a transaction yielding rows records
+type rowsSession struct {
+	neo4j.Session
+	rows int
+}
+
+func (s rowsSession) ReadTransaction(work neo4j.TransactionWork, _ ...func(*neo4j.TransactionConfig)) (interface{}, error) {
+	return work(rowsTx{rows: s.rows})
+}
+
+type rowsTx struct {
+	neo4j.Transaction
+	rows int
+}
+
+func (tx rowsTx) Run(string, map[string]interface{}) (neo4j.Result, error) {
+	return &rowsResult{left: tx.rows}, nil
+}
+
+type rowsResult struct {
+	neo4j.Result
+	left int
+}
+
+func (r *rowsResult) Next() bool {
+	r.left--
+	return r.left >= 0
+}
+
+func TestSpanParenting(t *testing.T) {
+	spans := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans)))
+
+	ctx, request := otel.Tracer("test").Start(context.Background(), "POST /calc")
+	db := Env{}.withContext(ctx)
+
+	session := measured{Session: rowsSession{rows: 3}, method: "snapshotModels", ctx: db.requestContext()}
+	session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, _ := tx.Run("MATCH (n) RETURN n", nil)
+		for result.Next() {
+		}
+		return nil, nil
+	})
+	calcSpan(db, "PayoutsFor", model.Space{Fields: []string{"a", "b"}}, 3).End()
+	request.End()
+
+	byName := make(map[string]tracetest.SpanStub)
+	for _, span := range spans.GetSpans() {
+		byName[span.Name] = span
+	}
+
+	root := byName["POST /calc"].SpanContext.SpanID()
+	for _, name := range []string{"neo4j snapshotModels", "calc PayoutsFor"} {
+		span, ok := byName[name]
+		if !ok {
+			t.Fatalf("no %q span in %v", name, byName)
+		}
+		if span.Parent.SpanID() != root {
+			t.Errorf("%q is not a child of the request span", name)
+		}
+	}
+
+	if rows := attr(byName["neo4j snapshotModels"].Attributes, "db.neo4j.rows"); rows.AsInt64() != 3 {
+		t.Errorf("db.neo4j.rows = %v, want 3", rows.Emit())
+	}
+	if kind := attr(byName["calc PayoutsFor"].Attributes, "space.kind"); kind.AsString() != model.Categorical {
+		t.Errorf("space.kind = %v, want %s", kind.Emit(), model.Categorical)
+	}
+}
+
+func attr(attrs []attribute.KeyValue, key string) attribute.Value {
+	for _, kv := range attrs {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
@@ -0,0 +1,175 @@
+package route
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"riverboat/http/calc"
+	"riverboat/model"
+	"strconv"
+	"strings"
+)
+
+// operations shared by the handlers and the riverboat CLI
+
+// the request is well formed but conflicts with stored state
+type conflict struct {
+	error
+}
+
+// payouts from a newer snapshot were posted while these were computed
+var errSuperseded = conflict{errors.New("newer payouts are already posted")}
+
+// a submission expected a model version other than the current one
+type versionConflict struct {
+	current  int64
+	expected []int64
+}
+
+func (v versionConflict) Error() string {
+	if len(v.expected) == 1 && v.expected[0] == existingVersion {
+		return "no model to replace"
+	}
+	listed := make([]string, len(v.expected))
+	for i, version := range v.expected {
+		listed[i] = strconv.FormatInt(version, 10)
+	}
+	return fmt.Sprintf("model is at version %d, not %s", v.current, strings.Join(listed, " or "))
+}
+
+// whether a player's current model version satisfies any expected one,
+// trivially so when none are expected
+func matches(current int64, expected []int64) bool {
+	if len(expected) == 0 {
+		return true
+	}
+	for _, version := range expected {
+		if version == current || version == existingVersion && current > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// the status a submission ends in: a failed If-Match is a failed
+// precondition, a stale expected_version a conflict
+func submitStatus(err error, header bool) int {
+	var stale versionConflict
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case !errors.As(err, &stale):
+		return http.StatusBadRequest
+	case header:
+		return http.StatusPreconditionFailed
+	}
+	return http.StatusConflict
+}
+
+// recompute and post a space's payouts from its stored terms
+func Recalc(db Controls, suuid string) (map[string]map[string]float64, error) {
+	space, err := db.getSpace(suuid)
+	if err != nil {
+		return nil, err
+	}
+	if space.Resolved != 0 {
+		return nil, conflict{errors.New("space is closed")}
+	}
+	return recompute(db, space)
+}
+
+// settle a space on weights -> field: share, or void it when weights is nil
+func ResolveSpace(db Controls, suuid string, weights map[string]float64) (string, error) {
+	space, err := db.getSpace(suuid)
+	if err != nil {
+		return "", err
+	}
+
+	if weights == nil {
+		if space.Resolved != 0 {
+			return "", conflict{errors.New("space is already resolved")}
+		}
+		return db.voidSpace(suuid)
+	}
+
+	var parent model.Space
+	if space.Parent != "" {
+		if parent, err = db.getSpace(space.Parent); err != nil {
+			return "", err
+		}
+	}
+
+	if err := model.CheckResolution(space, parent, weights); err != nil {
+		return "", conflict{err}
+	}
+
+	models, err := db.mapModels(suuid)
+	if err != nil {
+		return "", err
+	}
+
+	// settle on the weighted mix of each field's payout
+	span := calcSpan(db, "PayoutsFor", space, len(models))
+	payouts, _ := calc.PayoutsFor(space.Kind, space.Pattern, models, space.Fields, space.Stake)
+	span.End()
+
+	winnings := make(map[string]float64)
+	for name, payout := range payouts {
+		for field, weight := range model.Settlement(space, weights) {
+			winnings[name] += weight * payout[field]
+		}
+	}
+
+	return db.settleSpace(suuid, weights, winnings)
+}
+
+// apply every schema migration newer than the stored version
+func Migrate(db Controls) (int, error) {
+	current, err := db.schemaVersion()
+	if err != nil {
+		return 0, err
+	}
+
+	for version := current; version < len(migrations); version++ {
+		if err := db.migrate(version+1, migrations[version]); err != nil {
+			return version, fmt.Errorf("migration %d: %w", version+1, err)
+		}
+	}
+	return len(migrations), nil
+}
+
+// demo circle with players and an open space, returns the circle's uuid
+func Seed(db Controls, players int) (string, error) {
+	if players < 1 {
+		return "", errors.New("seed needs at least one player")
+	}
+	return db.seed(players, botMoney)
+}
+
+func Export(db Controls) (model.Dump, error) {
+	return db.export()
+}
+
+func Import(db Controls, dump model.Dump) (string, error) {
+	return db.load(dump)
+}
+
+// every ledger invariant that does not hold, empty when the books balance
+func CheckLedger(db Controls) ([]string, error) {
+	var problems []string
+	for _, check := range ledgerChecks {
+		found, err := db.audit(check.query)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", check.name, err)
+		}
+		for _, detail := range found {
+			problems = append(problems, check.name+": "+detail)
+		}
+	}
+	return problems, nil
+}
+
+// open spaces, active players and escrow across the whole graph
+func Totals(db Controls) (model.Totals, error) {
+	return db.totals()
+}
@@ -0,0 +1,96 @@
+package route
+
+import (
+	"context"
+	"math"
+	"riverboat/http/calc"
+	"riverboat/model"
+	"testing"
+)
+
+func TestSeedNeedsPlayers(t *testing.T) {
+	// a nil Controls panics if Seed gets as far as the database
+	for _, players := range []int{0, -3} {
+		if _, err := Seed(requested{}, players); err == nil {
+			t.Errorf("seeded with %d players", players)
+		}
+	}
+}
+
+// Controls holding spaces and their models in memory, recording what
+// settling and voiding would write
+type ledger struct {
+	Controls
+	spaces  map[string]model.Space
+	models  map[string]map[string]map[string]float64
+	settled map[string]map[string]float64 // suuid: winnings
+	voided  []string
+}
+
+func (l *ledger) getSpace(suuid string) (model.Space, error) {
+	return l.spaces[suuid], nil
+}
+
+func (l *ledger) mapModels(suuid string) (map[string]map[string]float64, error) {
+	return l.models[suuid], nil
+}
+
+func (l *ledger) snapshotModels(suuid string) (model.Snapshot, error) {
+	return model.Snapshot{Models: l.models[suuid]}, nil
+}
+
+func (l *ledger) settleSpace(suuid string, weights map[string]float64, winnings map[string]float64) (string, error) {
+	if l.settled == nil {
+		l.settled = make(map[string]map[string]float64)
+	}
+	l.settled[suuid] = winnings
+	return "Space resolved.", nil
+}
+
+func (l *ledger) voidSpace(suuid string) (string, error) {
+	l.voided = append(l.voided, suuid)
+	return "Space voided.", nil
+}
+
+func (l *ledger) requestContext() context.Context {
+	return context.Background()
+}
+
+func TestResolveUsesPattern(t *testing.T) {
+	crowd := map[string]map[string]float64{
+		"a": {"x": 80, "y": 20},
+		"b": {"x": 30, "y": 70},
+		"c": {"x": 50, "y": 50},
+	}
+	db := &ledger{
+		spaces: map[string]model.Space{
+			"w": {Uuid: "w", Kind: model.Categorical, Pattern: calc.WaterfallPattern, Fields: []string{"x", "y"}, Stake: 10},
+			"p": {Uuid: "p", Kind: model.Categorical, Pattern: calc.ParimutuelPattern, Fields: []string{"x", "y"}, Stake: 10},
+		},
+		models: map[string]map[string]map[string]float64{"w": crowd, "p": crowd},
+	}
+
+	for _, suuid := range []string{"w", "p"} {
+		if _, err := ResolveSpace(db, suuid, map[string]float64{"x": 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waterfall, parimutuel := db.settled["w"], db.settled["p"]
+	if math.Abs(waterfall["a"]-parimutuel["a"]) < 1e-9 {
+		t.Errorf("a won %g under both patterns", waterfall["a"])
+	}
+	// 30 in the pot, 16 of it on x, a put 8 there
+	if want := 30*8.0/16 - 10; math.Abs(parimutuel["a"]-want) > 1e-9 {
+		t.Errorf("a won %g under parimutuel, want %g", parimutuel["a"], want)
+	}
+	for pattern, winnings := range map[string]map[string]float64{"waterfall": waterfall, "parimutuel": parimutuel} {
+		sum := 0.0
+		for _, won := range winnings {
+			sum += won
+		}
+		if math.Abs(sum) > 1e-9 {
+			t.Errorf("%s settled %g more than was staked", pattern, sum)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package route
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"riverboat/model"
+	"strings"
+)
+
+// page size when a list request names none
+const pageLimit = 50
+
+// a cursor that was not issued for this list and sort
+var errCursor = errors.New("invalid cursor")
+
+// position after the last row of a page, base64 so clients treat it as opaque
+type cursor struct {
+	Sort string      `json:"s"`
+	Key  interface{} `json:"k"`
+	Uuid string      `json:"u"`
+}
+
+// keyset paging over rows ordered by a sort key, ties broken by uuid
+type paging struct {
+	sort  string // the sort param, named in cursors so they can't cross sorts
+	key   string // Cypher expression the rows are ordered by
+	desc  bool
+	after *cursor
+	limit int // 0 reads every row
+}
+
+// sortable fields as Cypher over the row variable
+var sortKeys = map[string]string{
+	"name":    "%s.name",
+	"created": "%s.created", // set on creation or by migration 6
+	"money":   "%s.money",
+}
+
+// paging for query over row, sorting by the first allowed field by default
+func page(query model.ListQuery, row string, allowed ...string) (paging, error) {
+	sort := query.Sort
+	if sort == "" {
+		sort = allowed[0]
+	}
+	field := strings.TrimPrefix(sort, "-")
+
+	known := false
+	for _, name := range allowed {
+		known = known || name == field
+	}
+	if !known {
+		return paging{}, errors.New("cannot sort by " + field)
+	}
+
+	p := paging{
+		sort:  sort,
+		key:   strings.ReplaceAll(sortKeys[field], "%s", row),
+		desc:  strings.HasPrefix(sort, "-"),
+		limit: query.Limit,
+	}
+
+	if query.Cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(query.Cursor)
+		if err != nil {
+			return paging{}, errCursor
+		}
+		var after cursor
+		if err := json.Unmarshal(raw, &after); err != nil || after.Sort != sort {
+			return paging{}, errCursor
+		}
+		p.after = &after
+	}
+	return p, nil
+}
+
+// WHERE for the clause before, which binds vars and key, then a WITH
+// ordering and limiting to one row past the page, which tells whether
+// another page follows; vars[0] is the row the uuid is read from
+func (p paging) window(vars ...string) string {
+	row := vars[0]
+	compare := ">"
+	if p.desc {
+		compare = "<"
+	}
+	clauses := `
+		WHERE $after_uuid IS NULL OR key ` + compare + ` $after_key
+			OR (key = $after_key AND ` + row + `.uuid ` + compare + ` $after_uuid)
+		WITH ` + strings.Join(vars, ", ") + `, key ` + p.order(row)
+	if p.limit > 0 {
+		clauses += ` LIMIT $limit`
+	}
+	return clauses
+}
+
+// ORDER BY key and row uuid in the paging's direction
+func (p paging) order(row string) string {
+	if p.desc {
+		return "ORDER BY key DESC, " + row + ".uuid DESC"
+	}
+	return "ORDER BY key, " + row + ".uuid"
+}
+
+// params with the cursor and limit window() refers to
+func (p paging) params(params map[string]interface{}) map[string]interface{} {
+	params["after_key"], params["after_uuid"] = nil, nil
+	if p.after != nil {
+		params["after_key"], params["after_uuid"] = p.after.Key, p.after.Uuid
+	}
+	params["limit"] = p.limit + 1
+	return params
+}
+
+// how many of the fetched rows belong on the page and the cursor after
+// them, empty on the last page
+func (p paging) cut(keys []interface{}, uuids []string) (int, string) {
+	if p.limit == 0 || len(uuids) <= p.limit {
+		return len(uuids), ""
+	}
+
+	last := p.limit - 1
+	raw, _ := json.Marshal(cursor{Sort: p.sort, Key: keys[last], Uuid: uuids[last]})
+	return p.limit, base64.RawURLEncoding.EncodeToString(raw)
+}
@@ -0,0 +1,75 @@
+package route
+
+import (
+	"errors"
+	"riverboat/model"
+	"strings"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	first, err := page(model.ListQuery{Sort: "-created", Limit: 2}, "space", "created", "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.key != "space.created" || !first.desc {
+		t.Errorf("paging %+v, want space.created descending", first)
+	}
+
+	// one row past the limit means another page follows
+	n, next := first.cut([]interface{}{int64(30), int64(20), int64(10)}, []string{"s3", "s2", "s1"})
+	if n != 2 || next == "" {
+		t.Fatalf("cut to %d rows with cursor %q, want 2 and a cursor", n, next)
+	}
+
+	second, err := page(model.ListQuery{Sort: "-created", Limit: 2, Cursor: next}, "space", "created", "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := second.params(map[string]interface{}{})
+	if params["after_key"] != float64(20) || params["after_uuid"] != "s2" || params["limit"] != 3 {
+		t.Errorf("params %v, want to continue after s2 at 20", params)
+	}
+	if !strings.Contains(second.window("space"), "key < $after_key") {
+		t.Error("descending window does not page downwards")
+	}
+
+	if n, last := second.cut([]interface{}{int64(10)}, []string{"s1"}); n != 1 || last != "" {
+		t.Errorf("last page cut to %d rows with cursor %q, want 1 and none", n, last)
+	}
+}
+
+func TestCursorRejected(t *testing.T) {
+	issued, _ := page(model.ListQuery{Sort: "name", Limit: 1}, "circle", "name", "created")
+	_, byName := issued.cut([]interface{}{"a", "b"}, []string{"c1", "c2"})
+
+	for name, cursor := range map[string]string{
+		"other sort":  byName,
+		"not base64":  "%%%",
+		"not json":    "bm90IGpzb24",
+		"wrong shape": "WzEsMl0",
+	} {
+		_, err := page(model.ListQuery{Sort: "created", Cursor: cursor}, "circle", "name", "created")
+		if !errors.Is(err, errCursor) {
+			t.Errorf("%s: got %v, want errCursor", name, err)
+		}
+	}
+}
+
+func TestPageSort(t *testing.T) {
+	p, err := page(model.ListQuery{}, "player", "name", "money")
+	if err != nil || p.sort != "name" || p.desc {
+		t.Errorf("default paging %+v, %v, want ascending by the first allowed field", p, err)
+	}
+	if _, err := page(model.ListQuery{Sort: "money"}, "circle", "name", "created"); err == nil {
+		t.Error("sorted by a field the list does not allow")
+	}
+
+	// limit 0 reads every row, with no cursor
+	if n, next := p.cut([]interface{}{"a", "b"}, []string{"p1", "p2"}); n != 2 || next != "" {
+		t.Errorf("unlimited cut to %d rows with cursor %q", n, next)
+	}
+	if strings.Contains(p.window("player"), "LIMIT") {
+		t.Error("unlimited window has a LIMIT")
+	}
+}
@@ -1,24 +1,110 @@
 package route
 
 import (
+	"riverboat/model"
 	"strconv"
 	"strings"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 )
 
+// starting balance for bot players
+const botMoney = 1000.0
+
+// an expected version that matches any model as long as there is one
+// (If-Match: *); no expected versions at all match whatever is there
+const existingVersion = -2
+
+// the versions an If-Match header lists, accepting weak and quoted tags
+func ifMatch(header string) ([]int64, bool) {
+	var versions []int64
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.Trim(strings.TrimPrefix(strings.TrimSpace(tag), "W/"), `"`)
+		if tag == "*" {
+			return []int64{existingVersion}, true
+		}
+		version, err := strconv.ParseInt(tag, 10, 64)
+		if err != nil || version < 0 {
+			return nil, false
+		}
+		versions = append(versions, version)
+	}
+	return versions, true
+}
+
+// the entity tag for a model version
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
 const (
-	postModelQuery = `
+	// bumping models_version write-locks the space, so submissions to it
+	// queue up here and each sees the model the one before it wrote; only
+	// players who joined the space's circle may submit, as before revisions
+	lockModelQuery = `
 		MATCH (player:Player {uuid: $puuid})-->(c:Circle)-->(space:Space {uuid: $suuid})
+		WITH DISTINCT player, space
+		SET space.models_version = coalesce(space.models_version, 0) + 1
 		WITH player, space
-		MERGE (player)-[:SETS]->(model:Model)-[:FOR]->(space) SET model = {block}
-		RETURN model
+		OPTIONAL MATCH (player)-[:SETS]->(prev:Model)-[:FOR]->(space)
+		RETURN coalesce(prev.version, 0) AS current
+	`
+
+	// a model submitted before revision history (migration 2) is made the
+	// newest revision before it is retired, rather than losing its only
+	// label; expects prev and latest, the highest numbered version, in scope
+	adoptLegacyModel = `
+		FOREACH (legacy IN CASE WHEN prev IS NULL OR prev:ModelRevision THEN [] ELSE [prev] END |
+			SET legacy:ModelRevision, legacy.uuid = randomUUID(),
+				legacy.created = coalesce(legacy.created, timestamp()), legacy.version = latest + 1)
+	`
+
+	postModelQuery = `
+		MATCH (player:Player {uuid: $puuid}), (space:Space {uuid: $suuid})
+		OPTIONAL MATCH (player)-[:SETS]->(old:ModelRevision)-[:FOR]->(space)
+		WITH player, space, coalesce(max(old.version), 0) AS latest
+		OPTIONAL MATCH (player)-[:SETS]->(prev:Model)-[:FOR]->(space)
+		` + adoptLegacyModel + `
+		WITH player, space, prev, coalesce(prev.version, latest) + 1 AS version
+		REMOVE prev:Model
+		SET prev.current = false, prev.retired = timestamp()
+		CREATE (player)-[:SETS]->(model:Model:ModelRevision {block})-[:FOR]->(space)
+		SET model.uuid = randomUUID(), model.created = timestamp(), model.current = true,
+			model.version = version
+		FOREACH (p IN CASE WHEN prev IS NULL THEN [] ELSE [prev] END | CREATE (model)-[:PREVIOUS]->(p))
+		MERGE (player)-[e:ESCROWED]->(space)
+		ON CREATE SET e.amount = space.stake, e.at = timestamp(), player.money = player.money - space.stake
+		RETURN model.version AS version
 	`
 
+	// payouts point at the exact revision they were computed from
 	postPayoutQuery = `
 		MATCH (player:Player {name: $name})-->(c:Circle)-->(space:Space {uuid: $suuid})
-		WITH player, space
+		WITH DISTINCT player, space
 		MERGE (space)-[:SETS]->(payout:Payout)-[:FOR]->(player) SET payout = {block}
+		WITH payout
+		OPTIONAL MATCH (payout)-[old:FROM]->(:ModelRevision)
+		DELETE old
+		WITH DISTINCT payout
+		OPTIONAL MATCH (rev:ModelRevision {uuid: $ruuid})
+		FOREACH (r IN CASE WHEN rev IS NULL THEN [] ELSE [rev] END | CREATE (payout)-[:FROM]->(r))
 		RETURN payout
 	`
+
+	// void every unresolved space under $roots, refunding escrow and dropping payouts
+	voidQuery = `
+		UNWIND $roots AS root
+		MATCH (:Space {uuid: root})<-[:DEPENDS_ON*0..]-(voided:Space)
+		WITH DISTINCT voided WHERE voided.resolved IS NULL
+		SET voided.void = true, voided.resolved = timestamp()
+		WITH voided
+		OPTIONAL MATCH (p:Player)-[e:ESCROWED]->(voided)
+		WHERE e.settled IS NULL
+		SET p.money = p.money + e.amount, e.settled = timestamp(), e.paid = e.amount
+		WITH DISTINCT voided
+		OPTIONAL MATCH (voided)-[:SETS]->(payout:Payout)
+		DETACH DELETE payout
+	`
 )
 
 // create string to add to cypher query
@@ -28,7 +114,8 @@ func formatProps(model map[string]float64, query string) string {
 
 	for str, val := range model {
 		float := strconv.FormatFloat(val, 'f', 1, 64)
-		line := str + ": " + float + ", "
+		key := "`" + strings.ReplaceAll(str, "`", "``") + "`" // fields may hold any label
+		line := key + ": " + float + ", "
 		spread += line
 	}
 
@@ -47,6 +134,155 @@ func assertArray(list []interface{}) []string {
 	return array
 }
 
+// keep only the float certainties of a Model or Payout node,
+// skipping revision metadata such as uuid, created and current
+func assertProps(props map[string]interface{}) map[string]float64 {
+
+	spread := make(map[string]float64)
+	for str, val := range props {
+		if float, ok := val.(float64); ok {
+			spread[str] = float
+		}
+	}
+	return spread
+}
+
+// every property is checked, a revision written by hand or before a
+// migration ran may lack any of them
+func parseRevision(props map[string]interface{}) model.ModelRevision {
+	revision := model.ModelRevision{Model: assertProps(props)}
+	if uuid, ok := props["uuid"].(string); ok {
+		revision.Uuid = uuid
+	}
+	if created, ok := props["created"].(int64); ok {
+		revision.Created = created
+	}
+	if current, ok := props["current"].(bool); ok {
+		revision.Current = current
+	}
+	if version, ok := props["version"].(int64); ok {
+		revision.Version = version
+	}
+	if retired, ok := props["retired"].(int64); ok {
+		revision.Retired = retired
+	}
+	return revision
+}
+
+// whether two spaces would produce the same payouts from the same models
+func sameTerms(a model.Space, b model.Space) bool {
+	if a.Pattern != b.Pattern || a.Stake != b.Stake || len(a.Fields) != len(b.Fields) {
+		return false
+	}
+	for i := range a.Fields {
+		if a.Fields[i] != b.Fields[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// copy kind and range onto a space, legacy spaces are categorical
+func kindProps(space *model.Space, props map[string]interface{}) {
+	space.Kind = model.Categorical
+	if kind, ok := props["kind"].(string); ok {
+		space.Kind = kind
+	}
+	if min, ok := props["min"].(float64); ok {
+		space.Min = min
+	}
+	if max, ok := props["max"].(float64); ok {
+		space.Max = max
+	}
+	if step, ok := props["step"].(float64); ok {
+		space.Step = step
+	}
+}
+
+// copy resolution state onto a space when it has been resolved
+func resolveProps(space *model.Space, props map[string]interface{}) {
+	if resolution, ok := props["resolution"].(string); ok {
+		space.Resolution = resolution
+	}
+	if resolved, ok := props["resolved"].(int64); ok {
+		space.Resolved = resolved
+	}
+	if void, ok := props["void"].(bool); ok {
+		space.Void = void
+	}
+
+	// weighted resolutions keep fields and weights side by side
+	fields, ok := props["resolution_fields"].([]interface{})
+	mix, _ := props["resolution_weights"].([]interface{})
+	if ok && len(fields) == len(mix) {
+		space.Weights = make(map[string]float64)
+		for i, field := range fields {
+			space.Weights[field.(string)] = mix[i].(float64)
+		}
+	} else if space.Resolution != "" {
+		space.Weights = map[string]float64{space.Resolution: 1}
+	}
+}
+
+// copy the parent link returned alongside a space, if it has one
+func dependsProps(space *model.Space, record *neo4j.Record) {
+	if parent, ok := record.Get("parent"); ok && parent != nil {
+		space.Parent = parent.(string)
+	}
+	if condition, ok := record.Get("condition"); ok && condition != nil {
+		space.Condition = condition.(string)
+	}
+}
+
+// nest conditional spaces under their parents, returning the roots
+func nestSpaces(spaces []model.Space) []model.Space {
+	children := make(map[string][]model.Space)
+	known := make(map[string]bool)
+	for _, space := range spaces {
+		known[space.Uuid] = true
+	}
+
+	var roots []model.Space
+	for _, space := range spaces {
+		if space.Parent != "" && known[space.Parent] {
+			children[space.Parent] = append(children[space.Parent], space)
+		} else {
+			roots = append(roots, space)
+		}
+	}
+
+	var attach func(space model.Space) model.Space
+	attach = func(space model.Space) model.Space {
+		for _, child := range children[space.Uuid] {
+			space.Children = append(space.Children, attach(child))
+		}
+		return space
+	}
+
+	for i, root := range roots {
+		roots[i] = attach(root)
+	}
+	return roots
+}
+
+func botProps(props map[string]interface{}) model.Bot {
+	return model.Bot{
+		Name:     props["name"].(string),
+		Uuid:     props["uuid"].(string),
+		Strategy: props["strategy"].(string),
+		Target:   props["target"].(string),
+	}
+}
+
+func webhookProps(props map[string]interface{}) model.Webhook {
+	return model.Webhook{
+		Uuid:    props["uuid"].(string),
+		Url:     props["url"].(string),
+		Events:  assertArray(props["events"].([]interface{})),
+		Created: props["created"].(int64),
+	}
+}
+
 func assertModel(json map[string]interface{}) map[string]float64 {
 
 	model := make(map[string]float64)
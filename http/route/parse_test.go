@@ -0,0 +1,143 @@
+package route
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"riverboat/model"
+	"testing"
+)
+
+func TestParseLegacyRevision(t *testing.T) {
+	// a model left from before revision history has nothing but its weights
+	revision := parseRevision(map[string]interface{}{"yes": 0.75, "no": 0.25})
+	if revision.Model["yes"] != 0.75 || revision.Uuid != "" || revision.Current {
+		t.Errorf("parsed %+v from a legacy model", revision)
+	}
+
+	revision = parseRevision(map[string]interface{}{
+		"yes": 1.0, "uuid": "r1", "created": int64(5), "current": true, "version": int64(2),
+	})
+	if revision.Uuid != "r1" || revision.Created != 5 || !revision.Current || revision.Version != 2 {
+		t.Errorf("parsed %+v", revision)
+	}
+	if _, ok := revision.Model["created"]; ok {
+		t.Error("bookkeeping properties ended up in the model")
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	cases := []struct {
+		header   string
+		versions []int64
+		ok       bool
+	}{
+		{`"3"`, []int64{3}, true},
+		{`3`, []int64{3}, true},
+		{` W/"3" `, []int64{3}, true},
+		{`"0"`, []int64{0}, true},
+		{`*`, []int64{existingVersion}, true},
+		{`"1", W/"4",7`, []int64{1, 4, 7}, true},
+		{`"2", *`, []int64{existingVersion}, true},
+		{``, nil, false},
+		{`"v3"`, nil, false},
+		{`"-1"`, nil, false},
+		{`"1", "x"`, nil, false},
+		{`"1",`, nil, false},
+	}
+	for _, c := range cases {
+		versions, ok := ifMatch(c.header)
+		if ok != c.ok || ok && !reflect.DeepEqual(versions, c.versions) {
+			t.Errorf("ifMatch(%q) = %v, %v, want %v, %v", c.header, versions, ok, c.versions, c.ok)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	cases := []struct {
+		current  int64
+		expected []int64
+		want     bool
+	}{
+		{0, nil, true}, // no header, last write wins
+		{4, nil, true},
+		{3, []int64{3}, true},
+		{4, []int64{3}, false},
+		{0, []int64{0}, true}, // expected no model yet
+		{0, []int64{existingVersion}, false},
+		{1, []int64{existingVersion}, true},
+		{4, []int64{1, 4, 7}, true},
+		{5, []int64{1, 4, 7}, false},
+	}
+	for _, c := range cases {
+		if got := matches(c.current, c.expected); got != c.want {
+			t.Errorf("matches(%d, %v) = %v", c.current, c.expected, got)
+		}
+	}
+}
+
+func TestVersionConflict(t *testing.T) {
+	cases := []struct {
+		conflict versionConflict
+		message  string
+	}{
+		{versionConflict{current: 0, expected: []int64{existingVersion}}, "no model to replace"},
+		{versionConflict{current: 5, expected: []int64{3}}, "model is at version 5, not 3"},
+		{versionConflict{current: 5, expected: []int64{1, 4}}, "model is at version 5, not 1 or 4"},
+	}
+	for _, c := range cases {
+		if got := c.conflict.Error(); got != c.message {
+			t.Errorf("%+v reads %q, want %q", c.conflict, got, c.message)
+		}
+	}
+}
+
+func TestSubmitStatus(t *testing.T) {
+	stale := versionConflict{current: 5, expected: []int64{3}}
+	cases := []struct {
+		err    error
+		header bool
+		status int
+	}{
+		{nil, true, http.StatusOK},
+		{nil, false, http.StatusOK},
+		{stale, true, http.StatusPreconditionFailed},
+		{stale, false, http.StatusConflict},
+		{errors.New("player is not in the space's circle"), true, http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		if got := submitStatus(c.err, c.header); got != c.status {
+			t.Errorf("submitStatus(%v, %v) = %d, want %d", c.err, c.header, got, c.status)
+		}
+	}
+}
+
+func TestSameTerms(t *testing.T) {
+	stored := model.Space{Uuid: "s", Pattern: "waterfall", Fields: []string{"x", "y"}, Stake: 10, Resolved: 0}
+
+	changed := func(change func(*model.Space)) model.Space {
+		terms := stored
+		terms.Fields = append([]string(nil), stored.Fields...)
+		change(&terms)
+		return terms
+	}
+	cases := []struct {
+		name  string
+		terms model.Space
+		same  bool
+	}{
+		{"identical", changed(func(*model.Space) {}), true},
+		{"other metadata", changed(func(s *model.Space) { s.Name, s.Resolved = "renamed", 5 }), true},
+		{"pattern", changed(func(s *model.Space) { s.Pattern = "parimutuel" }), false},
+		{"stake", changed(func(s *model.Space) { s.Stake = 20 }), false},
+		{"field added", changed(func(s *model.Space) { s.Fields = append(s.Fields, "z") }), false},
+		{"field renamed", changed(func(s *model.Space) { s.Fields[1] = "z" }), false},
+		{"fields reordered", changed(func(s *model.Space) { s.Fields = []string{"y", "x"} }), false},
+		{"no fields", changed(func(s *model.Space) { s.Fields = nil }), false},
+	}
+	for _, c := range cases {
+		if got := sameTerms(stored, c.terms); got != c.same {
+			t.Errorf("%s: sameTerms = %v", c.name, got)
+		}
+	}
+}
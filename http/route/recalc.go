@@ -0,0 +1,100 @@
+package route
+
+import (
+	"errors"
+	"riverboat/http/calc"
+	"riverboat/http/hub"
+	"riverboat/model"
+	"sync"
+	"time"
+)
+
+// recomputes a space's payouts in the background once its models
+// have stopped changing for Delay
+type Recalculator struct {
+	DB      Controls
+	Hub     *hub.Hub
+	Delay   time.Duration
+	Workers *Workers // recalcs due after shutdown begins are skipped
+
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+}
+
+func NewRecalculator(db Controls, events *hub.Hub, delay time.Duration) *Recalculator {
+	return &Recalculator{
+		DB:     db,
+		Hub:    events,
+		Delay:  delay,
+		timers: make(map[string]*time.Timer),
+	}
+}
+
+// hub listener: restart the space's timer on every model change
+func (rc *Recalculator) Touch(event hub.Event) {
+	if event.Kind != hub.ModelSubmitted && event.Kind != hub.ModelDeleted {
+		return
+	}
+
+	suuid := event.Topic
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if timer, ok := rc.timers[suuid]; ok {
+		timer.Stop()
+	}
+	rc.timers[suuid] = time.AfterFunc(rc.Delay, func() {
+		rc.mu.Lock()
+		delete(rc.timers, suuid)
+		rc.mu.Unlock()
+
+		rc.Workers.Spawn(func() {
+			space, err := rc.DB.getSpace(suuid)
+			if err != nil || space.Resolved != 0 {
+				return
+			}
+
+			payouts, err := recompute(rc.DB, space)
+			if errors.Is(err, errSuperseded) {
+				return // a later recalc already published its payouts
+			}
+			if err != nil {
+				logger(rc.DB).Error("recalc", "suuid", suuid, "err", err)
+				return
+			}
+			rc.Hub.Publish(suuid, hub.PayoutsComputed, payouts)
+		})
+	})
+}
+
+// compute payouts from the stored space and a snapshot of its current
+// models, then post them along with the revisions they came from
+func recompute(db Controls, space model.Space) (map[string]map[string]float64, error) {
+	snapshot, err := db.snapshotModels(space.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	span := calcSpan(db, "PayoutsFor", space, len(snapshot.Models))
+	payouts, err := calc.PayoutsFor(space.Kind, space.Pattern, snapshot.Models, space.Fields, space.Stake)
+	span.End()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.postPayouts(space.Uuid, payouts, snapshot)
+	return payouts, err
+}
+
+// payouts under hypothetical terms from a snapshot of the space's current
+// models, posting nothing
+func dryPayouts(db Controls, terms model.Space) (map[string]map[string]float64, error) {
+	snapshot, err := db.snapshotModels(terms.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	span := calcSpan(db, "PayoutsFor", terms, len(snapshot.Models))
+	defer span.End()
+	return calc.PayoutsFor(terms.Kind, terms.Pattern, snapshot.Models, terms.Fields, terms.Stake)
+}
@@ -0,0 +1,31 @@
+package route
+
+import (
+	"reflect"
+	"riverboat/http/calc"
+	"riverboat/model"
+	"testing"
+)
+
+func TestDryRunWritesNothing(t *testing.T) {
+	crowd := map[string]map[string]float64{
+		"a": {"x": 80, "y": 20},
+		"b": {"x": 30, "y": 70},
+	}
+	// posting payouts or settling goes through the nil Controls and panics
+	db := &ledger{models: map[string]map[string]map[string]float64{"s": crowd}}
+
+	terms := model.Space{Uuid: "s", Kind: model.Categorical, Pattern: calc.ParimutuelPattern, Fields: []string{"x", "y"}, Stake: 20}
+	payouts, err := dryPayouts(db, terms)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := calc.PayoutsFor(terms.Kind, terms.Pattern, crowd, terms.Fields, terms.Stake)
+	if !reflect.DeepEqual(payouts, want) {
+		t.Errorf("dry run paid %v, want %v", payouts, want)
+	}
+	if db.settled != nil || db.voided != nil {
+		t.Errorf("dry run settled %v and voided %v", db.settled, db.voided)
+	}
+}
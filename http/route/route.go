@@ -1,10 +1,21 @@
 package route
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"riverboat/http/bot"
 	"riverboat/http/calc"
+	"riverboat/http/hub"
+	"riverboat/http/logging"
+	"riverboat/http/score"
+	"riverboat/http/tracing"
+	"riverboat/http/webhook"
 	"riverboat/model"
+	"time"
 
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	"goyave.dev/goyave/v4"
@@ -12,40 +23,83 @@ import (
 
 type Env struct {
 	Driver neo4j.Driver
+	ctx    context.Context // parent for this Env's spans, set by withContext
 }
 
 type Handler struct {
-	DB Controls
+	DB    Controls
+	Hub   *hub.Hub
+	Ready *Readiness
+}
+
+// the backend with spans parented to r's trace
+func (h Handler) db(r *goyave.Request) Controls {
+	return h.DB.withContext(logging.NewContext(tracing.Context(r), logging.From(r)))
+}
+
+// the logger of the request db was made for, the default one otherwise
+func logger(db Controls) *slog.Logger {
+	return logging.FromContext(db.requestContext())
 }
 
 // implements functions for structs
 type Controls interface {
 	getSpace(suuid string) (model.Space, error)
-	listCircles() ([]model.Circle, error)
-	listSpaces(cuuid string) ([]model.Space, error)
-	listJoined(cuuid string) ([]model.Player, error)
-	listModels(suuid string) (map[string]map[string]float64, error)
-	listPayouts(suuid string) (map[string]map[string]float64, error)
+	listCircles(query model.ListQuery) (model.Page, error)
+	listSpaces(cuuid string, query model.ListQuery) (model.Page, error)
+	listJoined(cuuid string, query model.ListQuery) (model.Page, error)
+	listModels(suuid string, query model.ListQuery) (model.Page, error)
+	listPayouts(suuid string, query model.ListQuery) (model.PayoutsPage, error)
 	deleteModel(puuid string, suuid string) (string, error)
 	addRandom(cuuid string) (string, error)
 	join(puuid string, cuuid string) (string, error)
 	leave(puuid string, cuuid string) (string, error)
 	mapModels(suuid string) (map[string]map[string]float64, error)
-	submitModel(puuid string, suuid string, json map[string]float64) (string, error)
-	postPayouts(suuid string, payouts map[string]map[string]float64) (string, error)
+	mapModelsAsOf(suuid string, asOf int64) (map[string]map[string]float64, error)
+	listRevisions(puuid string, suuid string) ([]model.ModelRevision, error)
+	submitModel(puuid string, suuid string, json map[string]float64, expected []int64) (int64, error)
+	postPayouts(suuid string, payouts map[string]map[string]float64, snapshot model.Snapshot) (string, error)
+	snapshotModels(suuid string) (model.Snapshot, error)
+	postedModels(suuid string) (model.Snapshot, bool, error)
+	createWebhook(cuuid string, url string, events []string, secret string) (model.Webhook, error)
+	listWebhooks(cuuid string) ([]model.Webhook, error)
+	deleteWebhook(wuuid string) (string, error)
+	listDeliveries(wuuid string) ([]model.Delivery, error)
+	enqueueDeliveries(topic string, event string, payload string) error
+	dueDeliveries(limit int, lease time.Duration) ([]webhook.Job, error)
+	recordDelivery(duuid string, status string, code int, failure string, next time.Time) error
+	listResolved(cuuid string, from int64, to int64) ([]score.Outcome, error)
+	circleOf(suuid string) (string, error)
+	createSpace(cuuid string, space model.Space) (model.Space, error)
+	settleSpace(suuid string, weights map[string]float64, winnings map[string]float64) (string, error)
+	voidSpace(suuid string) (string, error)
+	getPlayer(puuid string) (model.Player, error)
+	createBot(cuuid string, strategy string, target string) (model.Bot, error)
+	listBots(cuuid string) ([]model.Bot, error)
+	schemaVersion() (int, error)
+	migrate(version int, statements []string) error
+	seed(players int, money float64) (string, error)
+	export() (model.Dump, error)
+	load(dump model.Dump) (string, error)
+	audit(query string) ([]string, error)
+	totals() (model.Totals, error)
 	getStatus() error
+	withContext(ctx context.Context) Controls
+	requestContext() context.Context
 }
 
 //
 // Initialization & Connection Functions
 //
 
+// 503 while Neo4j is unreachable, so a health check on / takes the
+// instance out of rotation; /readyz checks the schema and workers too
 func (h Handler) GetStatus(response *goyave.Response, r *goyave.Request) {
-	err := h.DB.getStatus()
+	err := h.db(r).getStatus()
 	if err == nil {
 		response.String(http.StatusOK, "online")
 	} else {
-		response.String(http.StatusOK, "offline")
+		response.String(http.StatusServiceUnavailable, "offline") // 503
 	}
 }
 
@@ -57,45 +111,213 @@ func (h Handler) Greeting(response *goyave.Response, r *goyave.Request) {
 // GET Functions
 //
 
+// receives CircleList
 func (h Handler) ListCircles(response *goyave.Response, r *goyave.Request) {
-	circles, err := h.DB.listCircles() // public circles
-	if err == nil {
-		response.JSON(http.StatusOK, circles)
-	}
+	circles, err := h.db(r).listCircles(listQuery(r)) // public circles
+	listed(response, circles, err, "Circles")
 }
 
+// receives SpaceList
 func (h Handler) ListSpaces(response *goyave.Response, r *goyave.Request) {
-	spaces, err := h.DB.listSpaces(r.Params["cuuid"]) // spawned by circle
-	if err == nil {
-		response.JSON(http.StatusOK, spaces)
-	}
+	spaces, err := h.db(r).listSpaces(r.Params["cuuid"], listQuery(r)) // spawned by circle
+	listed(response, spaces, err, "Spaces")
 }
 
 func (h Handler) GetSpace(response *goyave.Response, r *goyave.Request) {
-	space, err := h.DB.getSpace(r.Params["suuid"])
+	space, err := h.db(r).getSpace(r.Params["suuid"])
 	if err == nil {
 		response.JSON(http.StatusOK, space)
 	}
 }
 
+// receives PlayerList
 func (h Handler) ListJoined(response *goyave.Response, r *goyave.Request) {
-	joined, err := h.DB.listJoined(r.Params["cuuid"])
+	joined, err := h.db(r).listJoined(r.Params["cuuid"], listQuery(r))
+	listed(response, joined, err, "Players")
+}
+
+// receives NameList
+func (h Handler) ListModels(response *goyave.Response, r *goyave.Request) {
+	models, err := h.db(r).listModels(r.Params["suuid"], listQuery(r)) // joined/suuid
+	listed(response, models, err, "Models")
+}
+
+// receives NameList
+func (h Handler) ListPayouts(response *goyave.Response, r *goyave.Request) {
+	payouts, err := h.db(r).listPayouts(r.Params["suuid"], listQuery(r))
+	listed(response, payouts, err, "Payouts")
+}
+
+// paging and filters from a list request's query, a page at a time
+func listQuery(r *goyave.Request) model.ListQuery {
+	query := model.ListQuery{
+		Cursor: r.String("cursor"),
+		Limit:  pageLimit,
+		Sort:   r.String("sort"),
+		Owner:  r.String("owner"),
+		State:  r.String("state"),
+		Prefix: r.String("prefix"),
+	}
+	if r.Has("limit") {
+		query.Limit = r.Integer("limit")
+	}
+	return query
+}
+
+// a page as JSON, or why it could not be read
+func listed(response *goyave.Response, page interface{}, err error, what string) {
+	switch {
+	case err == nil:
+		response.JSON(http.StatusOK, page)
+	case errors.Is(err, errCursor):
+		response.String(http.StatusBadRequest, "Error: Invalid cursor.") // 400
+	default:
+		response.String(http.StatusBadRequest, "Error: Could not load "+what+".") // 400
+	}
+}
+
+func (h Handler) ListRevisions(response *goyave.Response, r *goyave.Request) {
+	revisions, err := h.db(r).listRevisions(r.Params["puuid"], r.Params["suuid"])
 	if err == nil {
-		response.JSON(http.StatusOK, joined)
+		response.JSON(http.StatusOK, revisions)
 	}
 }
 
-func (h Handler) ListModels(response *goyave.Response, r *goyave.Request) {
-	models, err := h.DB.listModels(r.Params["suuid"]) // joined/suuid
+// receives AsOf
+func (h Handler) PayoutsAsOf(response *goyave.Response, r *goyave.Request) {
+	suuid := r.Params["suuid"]
+	asOf := int64(r.Integer("as_of"))
+
+	space, err := h.db(r).getSpace(suuid)
+	if err != nil {
+		response.String(http.StatusBadRequest, "Error: Could not find Space.") // 400
+		return
+	}
+
+	models, err := h.db(r).mapModelsAsOf(suuid, asOf)
+	if err != nil {
+		response.String(http.StatusBadRequest, "Error: Could not load Models.") // 400
+		return
+	}
+
+	span := calcSpan(h.db(r), "PayoutsFor", space, len(models))
+	payouts, _ := calc.PayoutsFor(space.Kind, space.Pattern, models, space.Fields, space.Stake)
+	span.End()
+
+	response.JSON(http.StatusOK, payouts)
+}
+
+// the posted payouts explained, and whether models changed since they were
+type explained struct {
+	calc.Explanation
+	PayoutsVersion int64 `json:"payouts_version"`
+	Stale          bool  `json:"stale"`
+}
+
+// explains the payouts as posted, from the revisions they link to rather
+// than the current models, which may have moved on
+func (h Handler) ExplainPayouts(response *goyave.Response, r *goyave.Request) {
+	suuid := r.Params["suuid"]
+
+	space, err := h.db(r).getSpace(suuid)
+	if err != nil {
+		response.String(http.StatusBadRequest, "Error: Could not find Space.") // 400
+		return
+	}
+
+	posted, stale, err := h.db(r).postedModels(suuid)
+	if err != nil {
+		response.String(http.StatusBadRequest, "Error: Could not load Models.") // 400
+		return
+	}
+
+	span := calcSpan(h.db(r), "ExplainFor", space, len(posted.Models))
+	explanation, _ := calc.ExplainFor(space.Kind, space.Pattern, posted.Models, space.Fields, space.Stake)
+	span.End()
+
+	response.JSON(http.StatusOK, explained{
+		Explanation:    explanation,
+		PayoutsVersion: posted.Version,
+		Stale:          stale,
+	})
+}
+
+// receives Consensus
+func (h Handler) Consensus(response *goyave.Response, r *goyave.Request) {
+	suuid := r.Params["suuid"]
+	method, trim := calc.Mean, 0.1
+	if r.Has("method") {
+		method = r.String("method")
+	}
+	if r.Has("trim") {
+		trim = r.Numeric("trim")
+	}
+
+	space, err := h.db(r).getSpace(suuid)
+	if err != nil {
+		response.String(http.StatusBadRequest, "Error: Could not find Space.") // 400
+		return
+	}
+
+	models, err := h.db(r).mapModels(suuid)
+	if err != nil {
+		response.String(http.StatusBadRequest, "Error: Could not load Models.") // 400
+		return
+	}
+
+	var weights map[string]float64
+	if method == calc.LogOdds {
+		cuuid, err := h.db(r).circleOf(suuid)
+		if err != nil {
+			response.String(http.StatusBadRequest, "Error: Could not find Circle.") // 400
+			return
+		}
+		outcomes, err := h.db(r).listResolved(cuuid, 0, math.MaxInt64)
+		if err != nil {
+			response.String(http.StatusBadRequest, "Error: Could not load resolved Spaces.") // 400
+			return
+		}
+		weights = score.Weights(score.Leaderboard(outcomes), models)
+	}
+
+	crowd, err := calc.Consensus(models, space.Fields, method, trim, weights)
+
 	if err == nil {
-		response.JSON(http.StatusOK, models)
+		response.JSON(http.StatusOK, crowd)
+	} else {
+		response.String(http.StatusBadRequest, "Error: "+err.Error()) // 400
 	}
 }
 
-func (h Handler) ListPayouts(response *goyave.Response, r *goyave.Request) {
-	payouts, err := h.DB.listPayouts(r.Params["suuid"])
+// receives Window
+func (h Handler) Leaderboard(response *goyave.Response, r *goyave.Request) {
+	from, to := int64(0), int64(math.MaxInt64)
+	if r.Has("from") {
+		from = int64(r.Integer("from"))
+	}
+	if r.Has("to") {
+		to = int64(r.Integer("to"))
+	}
+
+	outcomes, err := h.db(r).listResolved(r.Params["cuuid"], from, to)
 	if err == nil {
-		response.JSON(http.StatusOK, payouts)
+		response.JSON(http.StatusOK, score.Leaderboard(outcomes))
+	} else {
+		response.String(http.StatusBadRequest, "Error: Could not load resolved Spaces.") // 400
+	}
+}
+
+func (h Handler) ListWebhooks(response *goyave.Response, r *goyave.Request) {
+	hooks, err := h.db(r).listWebhooks(r.Params["cuuid"])
+	if err == nil {
+		response.JSON(http.StatusOK, hooks)
+	}
+}
+
+func (h Handler) ListDeliveries(response *goyave.Response, r *goyave.Request) {
+	deliveries, err := h.db(r).listDeliveries(r.Params["wuuid"])
+	if err == nil {
+		response.JSON(http.StatusOK, deliveries)
 	}
 }
 
@@ -103,22 +325,60 @@ func (h Handler) ListPayouts(response *goyave.Response, r *goyave.Request) {
 // POST Functions
 //
 
-// receives Space
+// receives Space, only uuid is needed; fields, pattern and stake must match
+// the stored Space unless dry_run asks for a hypothetical, unsaved result
 func (h Handler) CalculatePayouts(response *goyave.Response, r *goyave.Request) {
-	fields := r.Data["fields"].([]string)
-	pattern := r.String("pattern")
-	stake := r.Numeric("stake")
 	suuid := r.String("uuid")
+	dryRun := r.Has("dry_run") && r.Bool("dry_run")
 
-	fmt.Println("calculating:", pattern)
+	space, err := h.db(r).getSpace(suuid)
+	if err != nil {
+		response.String(http.StatusBadRequest, "Error: Could not find Space.") // 400
+		return
+	}
 
-	models, _ := h.DB.mapModels(suuid)
-	payouts, _ := calc.Payouts(models, fields, stake)
-	result, err := h.DB.postPayouts(suuid, payouts) // change query
+	terms := space
+	if r.Has("fields") {
+		terms.Fields = r.Data["fields"].([]string)
+	}
+	if r.Has("pattern") {
+		terms.Pattern = r.String("pattern")
+	}
+	if r.Has("stake") {
+		terms.Stake = r.Numeric("stake")
+	}
 
-	if err == nil {
-		response.String(http.StatusOK, result)
-	} else {
+	logging.From(r).Info("calculating payouts", "pattern", terms.Pattern, "dry_run", dryRun)
+
+	if dryRun {
+		payouts, err := dryPayouts(h.db(r), terms)
+		if err != nil {
+			response.String(http.StatusBadRequest, "Error: Could not calculate payouts.") // 400
+			return
+		}
+		response.JSON(http.StatusOK, payouts)
+		return
+	}
+
+	if !sameTerms(space, terms) {
+		response.String(http.StatusConflict, "Error: Request disagrees with the stored Space.") // 409
+		return
+	}
+	if space.Resolved != 0 {
+		response.String(http.StatusConflict, "Error: Space is closed.") // 409
+		return
+	}
+
+	payouts, err := recompute(h.db(r), space)
+
+	var clash conflict
+	switch {
+	case err == nil:
+		h.Hub.Publish(suuid, hub.PayoutsComputed, payouts)
+		response.String(http.StatusOK, "Payouts posted.")
+	case errors.As(err, &clash):
+		response.String(http.StatusConflict, "Error: "+err.Error()+".") // 409
+	default:
 		response.String(http.StatusBadRequest, "Error: Could not calculate payouts.") // 400
 	}
 }
@@ -129,14 +389,50 @@ func (h Handler) SubmitModel(response *goyave.Response, r *goyave.Request) {
 	suuid := r.String("suuid")
 	spread := r.Object("model")
 
-	model := assertModel(spread)
+	certs := assertModel(spread)
+
+	space, err := h.db(r).getSpace(suuid)
+	if err != nil {
+		response.String(http.StatusBadRequest, "Error: Could not find Space.") // 400
+		return
+	}
+	if space.Resolved != 0 {
+		response.String(http.StatusConflict, "Error: Space is closed.") // 409
+		return
+	}
+	if err := model.CheckModel(space, certs); err != nil {
+		response.String(http.StatusUnprocessableEntity, "Error: "+err.Error()) // 422
+		return
+	}
 
-	res, err := h.DB.submitModel(puuid, suuid, model)
+	// If-Match wins over expected_version; neither means last write wins
+	var expected []int64
+	header := r.Header().Get("If-Match")
+	if header != "" {
+		versions, ok := ifMatch(header)
+		if !ok {
+			response.String(http.StatusBadRequest, "Error: If-Match must name model versions.") // 400
+			return
+		}
+		expected = versions
+	} else if r.Has("expected_version") {
+		expected = []int64{int64(r.Integer("expected_version"))}
+	}
 
-	if err == nil {
-		response.String(http.StatusOK, res)
-	} else {
-		response.String(http.StatusBadRequest, "Error: Bad submission.") // 400
+	version, err := h.db(r).submitModel(puuid, suuid, certs, expected)
+
+	var stale versionConflict
+	switch status := submitStatus(err, header != ""); status {
+	case http.StatusOK:
+		h.Hub.Publish(suuid, hub.ModelSubmitted, map[string]interface{}{"puuid": puuid, "model": certs, "version": version})
+		response.Header().Set("ETag", etag(version))
+		response.String(status, "Model submitted.")
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		errors.As(err, &stale)
+		response.Header().Set("ETag", etag(stale.current))
+		response.String(status, "Error: "+err.Error()+".") // 412 or 409
+	default:
+		response.String(status, "Error: Bad submission.") // 400
 	}
 }
 
@@ -149,9 +445,10 @@ func (h Handler) Join(response *goyave.Response, r *goyave.Request) {
 	puuid := r.String("puuid")
 	cuuid := r.String("cuuid")
 
-	res, err := h.DB.join(puuid, cuuid)
+	res, err := h.db(r).join(puuid, cuuid)
 
 	if err == nil {
+		h.Hub.Publish(cuuid, hub.PlayerJoined, map[string]string{"puuid": puuid})
 		response.String(http.StatusOK, res)
 	} else {
 		response.String(http.StatusBadRequest, "Error: Could not join Circle.") // 400
@@ -163,20 +460,26 @@ func (h Handler) Leave(response *goyave.Response, r *goyave.Request) {
 	puuid := r.String("puuid")
 	cuuid := r.String("cuuid")
 
-	res, err := h.DB.leave(puuid, cuuid)
+	res, err := h.db(r).leave(puuid, cuuid)
 
 	if err == nil {
+		h.Hub.Publish(cuuid, hub.PlayerLeft, map[string]string{"puuid": puuid})
 		response.String(http.StatusOK, res)
 	} else {
 		response.String(http.StatusBadRequest, "Error: Could not leave Circle.") // 400
 	}
 }
 
-// receives Circle
+// receives Random, with a strategy a new bot joins instead of a random player
 func (h Handler) AddRandom(response *goyave.Response, r *goyave.Request) {
 	cuuid := r.String("cuuid")
 
-	res, err := h.DB.addRandom(cuuid)
+	if r.Has("strategy") {
+		h.addBot(response, r, cuuid)
+		return
+	}
+
+	res, err := h.db(r).addRandom(cuuid)
 
 	if err == nil {
 		response.String(http.StatusOK, res)
@@ -185,16 +488,214 @@ func (h Handler) AddRandom(response *goyave.Response, r *goyave.Request) {
 	}
 }
 
+// bots submit to every open space straight away so the circle has activity
+func (h Handler) addBot(response *goyave.Response, r *goyave.Request, cuuid string) {
+	strategy := r.String("strategy")
+	target := ""
+	if r.Has("target") {
+		target = r.String("target")
+	}
+
+	if _, err := bot.Lookup(strategy, target); err != nil {
+		response.String(http.StatusUnprocessableEntity, "Error: "+err.Error()) // 422
+		return
+	}
+
+	player, err := h.db(r).createBot(cuuid, strategy, target)
+	if err != nil {
+		response.String(http.StatusBadRequest, "Error: Could not join Circle.") // 400
+		return
+	}
+	h.Hub.Publish(cuuid, hub.PlayerJoined, map[string]string{"puuid": player.Uuid})
+
+	spaces, err := h.db(r).listSpaces(cuuid, model.ListQuery{State: "open"})
+	if err == nil {
+		bots := Bots{DB: h.db(r), Hub: h.Hub}
+		for _, space := range openSpaces(spaces.Items.([]model.Space)) {
+			bots.play(player, space)
+		}
+	}
+
+	response.JSON(http.StatusOK, player)
+}
+
 // receives PlayerSpace
 func (h Handler) DeleteModel(response *goyave.Response, r *goyave.Request) {
 	puuid := r.String("puuid")
 	suuid := r.String("suuid")
 
-	res, err := h.DB.deleteModel(puuid, suuid)
+	space, err := h.db(r).getSpace(suuid)
+	if err != nil {
+		response.String(http.StatusBadRequest, "Error: Could not find Space.") // 400
+		return
+	}
+	if space.Resolved != 0 {
+		response.String(http.StatusConflict, "Error: Space is closed.") // 409
+		return
+	}
+
+	res, err := h.db(r).deleteModel(puuid, suuid)
 
 	if err == nil {
+		h.Hub.Publish(suuid, hub.ModelDeleted, map[string]string{"puuid": puuid})
 		response.String(http.StatusOK, res)
 	} else {
 		response.String(http.StatusBadRequest, "Error: Could not join delete Model") // 400
 	}
 }
+
+// receives Webhook
+func (h Handler) CreateWebhook(response *goyave.Response, r *goyave.Request) {
+	cuuid := r.String("cuuid")
+	target := r.String("url")
+	events := []string{} // empty subscribes to every event
+	if r.Has("events") {
+		events = r.Data["events"].([]string)
+	}
+
+	if err := webhook.CheckTarget(r.Request().Context(), target); err != nil {
+		response.String(http.StatusUnprocessableEntity, "Error: "+err.Error()+".") // 422
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		response.String(http.StatusInternalServerError, "Error: Could not create Webhook.") // 500
+		return
+	}
+
+	hook, err := h.db(r).createWebhook(cuuid, target, events, secret)
+
+	if err == nil {
+		response.JSON(http.StatusOK, hook)
+	} else {
+		response.String(http.StatusBadRequest, "Error: Could not create Webhook.") // 400
+	}
+}
+
+// receives Uuid
+func (h Handler) DeleteWebhook(response *goyave.Response, r *goyave.Request) {
+	res, err := h.db(r).deleteWebhook(r.String("uuid"))
+
+	if err == nil {
+		response.String(http.StatusOK, res)
+	} else {
+		response.String(http.StatusBadRequest, "Error: Could not delete Webhook.") // 400
+	}
+}
+
+// receives NewSpace
+func (h Handler) CreateSpace(response *goyave.Response, r *goyave.Request) {
+	space := model.Space{
+		Kind:    r.String("kind"),
+		Name:    r.String("name"),
+		Pattern: r.String("pattern"),
+		Stake:   r.Numeric("stake"),
+	}
+	if r.Has("description") {
+		space.Description = r.String("description")
+	}
+	if r.Has("fields") {
+		space.Fields = r.Data["fields"].([]string)
+	}
+	if r.Has("min") {
+		space.Min = r.Numeric("min")
+	}
+	if r.Has("max") {
+		space.Max = r.Numeric("max")
+	}
+	if r.Has("step") {
+		space.Step = r.Numeric("step")
+	}
+	if r.Has("parent") {
+		space.Parent = r.String("parent")
+	}
+	if r.Has("condition") {
+		space.Condition = r.String("condition")
+	}
+
+	if err := model.CheckSpace(&space); err != nil {
+		response.String(http.StatusUnprocessableEntity, "Error: "+err.Error()) // 422
+		return
+	}
+
+	cuuid := r.String("cuuid")
+	created, err := h.db(r).createSpace(cuuid, space)
+
+	if err == nil {
+		h.Hub.Publish(cuuid, hub.SpaceCreated, created)
+		response.JSON(http.StatusOK, created)
+	} else {
+		response.String(http.StatusBadRequest, "Error: Could not create Space.") // 400
+	}
+}
+
+// receives Resolve, with exactly one of field, weights or void
+func (h Handler) Resolve(response *goyave.Response, r *goyave.Request) {
+	suuid := r.String("suuid")
+	void := r.Has("void") && r.Bool("void")
+
+	var weights map[string]float64
+	switch {
+	case r.Has("field") && !r.Has("weights") && !void:
+		weights = map[string]float64{r.String("field"): 1}
+	case r.Has("weights") && !r.Has("field") && !void:
+		weights = assertModel(r.Object("weights"))
+	case void && !r.Has("field") && !r.Has("weights"):
+		// refund everyone, no weights to settle on
+	default:
+		response.String(http.StatusUnprocessableEntity, "Error: Resolve with one of field, weights or void.") // 422
+		return
+	}
+
+	res, err := ResolveSpace(h.db(r), suuid, weights)
+
+	var clash conflict
+	switch {
+	case err == nil:
+		h.Hub.Publish(suuid, hub.SpaceResolved, map[string]interface{}{"weights": weights, "void": void})
+		response.String(http.StatusOK, res)
+	case errors.As(err, &clash):
+		response.String(http.StatusConflict, "Error: "+err.Error()) // 409
+	default:
+		response.String(http.StatusBadRequest, "Error: Could not resolve Space.") // 400
+	}
+}
+
+// receives Simulation, nothing is written
+func (h Handler) Simulate(response *goyave.Response, r *goyave.Request) {
+	suuid := r.Params["suuid"]
+	certs := assertModel(r.Object("model"))
+
+	space, err := h.db(r).getSpace(suuid)
+	if err != nil {
+		response.String(http.StatusBadRequest, "Error: Could not find Space.") // 400
+		return
+	}
+	if err := model.CheckModel(space, certs); err != nil {
+		response.String(http.StatusUnprocessableEntity, "Error: "+err.Error()) // 422
+		return
+	}
+
+	player, err := h.db(r).getPlayer(r.String("puuid"))
+	if err != nil {
+		response.String(http.StatusBadRequest, "Error: Could not find Player.") // 400
+		return
+	}
+
+	models, err := h.db(r).mapModels(suuid)
+	if err != nil {
+		response.String(http.StatusBadRequest, "Error: Could not load Models.") // 400
+		return
+	}
+
+	span := calcSpan(h.db(r), "Simulate", space, len(models))
+	simulation, err := calc.Simulate(space.Kind, space.Pattern, models, space.Fields, space.Stake, player.Name, certs)
+	span.End()
+
+	if err == nil {
+		response.JSON(http.StatusOK, simulation)
+	} else {
+		response.String(http.StatusBadRequest, "Error: Could not simulate payouts.") // 400
+	}
+}
@@ -0,0 +1,97 @@
+package route
+
+// each migration is a list of statements run in order, schema and data
+// statements cannot share a transaction so every statement commits alone
+var migrations = [][]string{
+	// 1: uniqueness and lookup indexes
+	{
+		`CREATE CONSTRAINT player_uuid IF NOT EXISTS FOR (n:Player) REQUIRE n.uuid IS UNIQUE`,
+		`CREATE CONSTRAINT circle_uuid IF NOT EXISTS FOR (n:Circle) REQUIRE n.uuid IS UNIQUE`,
+		`CREATE CONSTRAINT space_uuid IF NOT EXISTS FOR (n:Space) REQUIRE n.uuid IS UNIQUE`,
+		`CREATE CONSTRAINT revision_uuid IF NOT EXISTS FOR (n:ModelRevision) REQUIRE n.uuid IS UNIQUE`,
+		`CREATE CONSTRAINT webhook_uuid IF NOT EXISTS FOR (n:Webhook) REQUIRE n.uuid IS UNIQUE`,
+		`CREATE CONSTRAINT delivery_uuid IF NOT EXISTS FOR (n:Delivery) REQUIRE n.uuid IS UNIQUE`,
+		`CREATE INDEX delivery_due IF NOT EXISTS FOR (n:Delivery) ON (n.status, n.next_attempt)`,
+	},
+	// 2: models submitted before revision history become current revisions
+	{
+		`MATCH (m:Model) WHERE NOT m:ModelRevision
+		 SET m:ModelRevision, m.uuid = randomUUID(),
+			 m.created = coalesce(m.created, timestamp()), m.current = true`,
+	},
+	// 3: spaces created before kinds and version counters
+	{
+		`MATCH (s:Space) WHERE s.kind IS NULL SET s.kind = 'categorical'`,
+		`MATCH (s:Space) WHERE s.models_version IS NULL
+		 SET s.models_version = 0, s.payouts_version = coalesce(s.payouts_version, 0)`,
+	},
+	// 4: revisions submitted before versions are numbered in order of creation
+	{
+		`MATCH (p:Player)-[:SETS]->(r:ModelRevision)-[:FOR]->(s:Space)
+		 WITH p, s, r ORDER BY r.created
+		 WITH p, s, collect(r) AS revisions
+		 WHERE none(r IN revisions WHERE r.version IS NOT NULL)
+		 FOREACH (i IN range(0, size(revisions) - 1) | SET (revisions[i]).version = i + 1)`,
+	},
+	// 5: indexes behind list sorting and filters
+	{
+		`CREATE INDEX circle_name IF NOT EXISTS FOR (n:Circle) ON (n.name)`,
+		`CREATE INDEX circle_owner IF NOT EXISTS FOR (n:Circle) ON (n.spawned_by)`,
+		`CREATE INDEX space_created IF NOT EXISTS FOR (n:Space) ON (n.created)`,
+		`CREATE INDEX player_name IF NOT EXISTS FOR (n:Player) ON (n.name)`,
+	},
+	// 6: spaces and circles from before created timestamps date from their
+	// first model or space, so lists can sort on the indexed property
+	{
+		`MATCH (s:Space) WHERE s.created IS NULL
+		 OPTIONAL MATCH (:Player)-[:SETS]->(r:ModelRevision)-[:FOR]->(s)
+		 WITH s, min(r.created) AS first
+		 SET s.created = coalesce(first, timestamp())`,
+		`MATCH (c:Circle) WHERE c.created IS NULL
+		 OPTIONAL MATCH (c)-[:SPAWNED]->(s:Space)
+		 WITH c, min(s.created) AS first
+		 SET c.created = coalesce(first, timestamp())`,
+		`CREATE INDEX circle_created IF NOT EXISTS FOR (n:Circle) ON (n.created)`,
+	},
+}
+
+// every query returns one detail row per broken invariant
+var ledgerChecks = []struct {
+	name  string
+	query string
+}{
+	{"payouts not zero-sum", `
+		MATCH (s:Space)-[:SETS]->(p:Payout)
+		UNWIND keys(p) AS field
+		WITH s, field, sum(p[field]) AS total
+		WHERE abs(total) > 0.01
+		RETURN s.uuid + ' ' + field + ' sums to ' + toString(total) AS detail
+	`},
+	{"settlement not zero-sum", `
+		MATCH (:Player)-[e:ESCROWED]->(s:Space)
+		WHERE e.settled IS NOT NULL AND NOT coalesce(s.void, false)
+		WITH s, sum(e.paid - e.amount) AS net
+		WHERE abs(net) > 0.01
+		RETURN s.uuid + ' paid out ' + toString(net) + ' more than it took in' AS detail
+	`},
+	{"escrow left on closed space", `
+		MATCH (p:Player)-[e:ESCROWED]->(s:Space)
+		WHERE s.resolved IS NOT NULL AND e.settled IS NULL
+		RETURN p.uuid + ' still has ' + toString(e.amount) + ' in ' + s.uuid AS detail
+	`},
+	{"escrow without a model", `
+		MATCH (p:Player)-[e:ESCROWED]->(s:Space)
+		WHERE e.settled IS NULL AND NOT (p)-[:SETS]->(:Model)-[:FOR]->(s)
+		RETURN p.uuid + ' has ' + toString(e.amount) + ' in ' + s.uuid AS detail
+	`},
+	{"model without escrow", `
+		MATCH (p:Player)-[:SETS]->(:Model)-[:FOR]->(s:Space)
+		WHERE s.resolved IS NULL AND NOT (p)-[:ESCROWED]->(s)
+		RETURN p.uuid + ' in ' + s.uuid AS detail
+	`},
+	{"negative balance", `
+		MATCH (p:Player)
+		WHERE p.money < 0
+		RETURN p.uuid + ' has ' + toString(p.money) AS detail
+	`},
+}
@@ -1,7 +1,12 @@
 package route
 
 import (
+	"errors"
+	"fmt"
+	"riverboat/http/score"
+	"riverboat/http/webhook"
 	"riverboat/model"
+	"time"
 
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 )
@@ -14,21 +19,39 @@ func (env Env) getStatus() error {
 	return env.Driver.VerifyConnectivity()
 }
 
-func (env Env) listCircles() ([]model.Circle, error) {
-	session := env.Driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+// one page of circles, optionally only those spawned by an owner
+func (env Env) listCircles(query model.ListQuery) (model.Page, error) {
+	paged, err := page(query, "circle", "name", "created")
+	if err != nil {
+		return model.Page{}, err
+	}
+
+	session := env.session("listCircles", neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close()
 
+	// a bare property predicate lets the planner walk the sort key's index
+	// in order instead of scanning every circle and sorting
+	filter := paged.key + " IS NOT NULL"
+	if query.Owner != "" {
+		filter += " AND circle.spawned_by = $owner"
+	}
+
 	records, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
 		result, err := tx.Run(`
-			MATCH (circle:Circle) 
-			RETURN circle
-		`, map[string]interface{}{})
+			MATCH (circle:Circle)
+			WHERE `+filter+`
+			WITH circle, `+paged.key+` AS key
+			`+paged.window("circle")+`
+			RETURN circle, key
+		`, paged.params(map[string]interface{}{"owner": query.Owner}))
 
 		if err != nil {
 			return nil, err
 		}
 
-		var circles []model.Circle
+		circles := []model.Circle{}
+		var keys []interface{}
+		var uuids []string
 		for result.Next() {
 			record := result.Record()
 			if value, ok := record.Get("circle"); ok {
@@ -43,31 +66,66 @@ func (env Env) listCircles() ([]model.Circle, error) {
 					// name	The Lab
 					// spawned_by	Yakub
 				}
+				if owner, ok := props["spawned_by"].(string); ok {
+					circle.Owner = owner
+				}
+				if created, ok := props["created"].(int64); ok {
+					circle.Created = created
+				}
+				key, _ := record.Get("key")
 				circles = append(circles, circle)
+				keys = append(keys, key)
+				uuids = append(uuids, circle.Uuid)
 			}
 		}
 
-		return circles, err
+		if err = result.Err(); err != nil {
+			return nil, err
+		}
+
+		kept, next := paged.cut(keys, uuids)
+		return model.Page{Items: circles[:kept], NextCursor: next}, nil
 	})
 
-	return records.([]model.Circle), err
+	if err != nil {
+		return model.Page{}, err
+	}
+
+	return records.(model.Page), nil
 }
 
-func (env Env) listSpaces(cuuid string) ([]model.Space, error) {
-	session := env.Driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+// one page of the spaces spawned by a circle, optionally only those in a
+// state; children are nested under parents on the same page
+func (env Env) listSpaces(cuuid string, query model.ListQuery) (model.Page, error) {
+	paged, err := page(query, "space", "created", "name")
+	if err != nil {
+		return model.Page{}, err
+	}
+
+	session := env.session("listSpaces", neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close()
 
 	records, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
 		result, err := tx.Run(`
 			MATCH (space:Space)<--(c:Circle {uuid: $cuuid})
-			RETURN space
-		`, map[string]interface{}{"cuuid": cuuid})
+			WHERE $state = ''
+				OR ($state = 'open' AND space.resolved IS NULL)
+				OR ($state = 'void' AND coalesce(space.void, false))
+				OR ($state = 'resolved' AND space.resolved IS NOT NULL AND NOT coalesce(space.void, false))
+			WITH space, `+paged.key+` AS key
+			`+paged.window("space")+`
+			OPTIONAL MATCH (space)-[d:DEPENDS_ON]->(parent:Space)
+			RETURN space, parent.uuid AS parent, d.field AS condition, key
+			`+paged.order("space")+`
+		`, paged.params(map[string]interface{}{"cuuid": cuuid, "state": query.State}))
 
 		if err != nil {
 			return nil, err
 		}
 
 		var spaces []model.Space
+		var keys []interface{}
+		var uuids []string
 		for result.Next() {
 			record := result.Record()
 			if value, ok := record.Get("space"); ok {
@@ -82,24 +140,44 @@ func (env Env) listSpaces(cuuid string) ([]model.Space, error) {
 					Uuid:        props["uuid"].(string),
 					Description: props["description"].(string),
 				}
+				kindProps(&space, props)
+				resolveProps(&space, props)
+				dependsProps(&space, record)
+				key, _ := record.Get("key")
 				spaces = append(spaces, space)
+				keys = append(keys, key)
+				uuids = append(uuids, space.Uuid)
 			}
 		}
 
-		return spaces, err
+		if err = result.Err(); err != nil {
+			return nil, err
+		}
+
+		kept, next := paged.cut(keys, uuids)
+		nested := nestSpaces(spaces[:kept])
+		if nested == nil {
+			nested = []model.Space{}
+		}
+		return model.Page{Items: nested, NextCursor: next}, nil
 	})
 
-	return records.([]model.Space), err
+	if err != nil {
+		return model.Page{}, err
+	}
+
+	return records.(model.Page), nil
 }
 
 func (env Env) getSpace(suuid string) (model.Space, error) {
-	session := env.Driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	session := env.session("getSpace", neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close()
 
 	records, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
 		result, err := tx.Run(`
-			MATCH (space:Space {uuid: $suuid}) 
-			RETURN space
+			MATCH (space:Space {uuid: $suuid})
+			OPTIONAL MATCH (space)-[d:DEPENDS_ON]->(parent:Space)
+			RETURN space, parent.uuid AS parent, d.field AS condition
 		`, map[string]interface{}{"suuid": suuid})
 
 		if err != nil {
@@ -107,43 +185,62 @@ func (env Env) getSpace(suuid string) (model.Space, error) {
 		}
 
 		var space model.Space
-		if result.Next() {
-			record := result.Record()
-			if value, ok := record.Get("space"); ok {
-				node := value.(neo4j.Node)
-				props := node.Props
-				fields := props["fields"].([]interface{})
-				object := model.Space{
-					Fields:  assertArray(fields),
-					Pattern: props["pattern"].(string),
-					Stake:   props["stake"].(float64),
-					Uuid:    props["uuid"].(string),
-				}
-				space = object
+		if !result.Next() {
+			return nil, errors.New("space not found: " + suuid)
+		}
+
+		record := result.Record()
+		if value, ok := record.Get("space"); ok {
+			node := value.(neo4j.Node)
+			props := node.Props
+			fields := props["fields"].([]interface{})
+			object := model.Space{
+				Fields:  assertArray(fields),
+				Pattern: props["pattern"].(string),
+				Stake:   props["stake"].(float64),
+				Uuid:    props["uuid"].(string),
 			}
+			kindProps(&object, props)
+			resolveProps(&object, props)
+			dependsProps(&object, record)
+			space = object
 		}
 		return space, err
 	})
 
-	return records.(model.Space), err
+	if err != nil {
+		return model.Space{}, err
+	}
+
+	return records.(model.Space), nil
 }
 
-// returns array of players
-func (env Env) listJoined(cuuid string) ([]model.Player, error) {
-	session := env.Driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+// one page of the players in a circle, optionally by name prefix
+func (env Env) listJoined(cuuid string, query model.ListQuery) (model.Page, error) {
+	paged, err := page(query, "player", "name", "money")
+	if err != nil {
+		return model.Page{}, err
+	}
+
+	session := env.session("listJoined", neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close()
 
 	people, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
 		result, err := tx.Run(`
 			MATCH (player:Player)-[:JOINED]->(c:Circle {uuid: $cuuid})
-			RETURN player
-			`, map[string]interface{}{"cuuid": cuuid})
+			WHERE player.name STARTS WITH $prefix
+			WITH player, `+paged.key+` AS key
+			`+paged.window("player")+`
+			RETURN player, key
+			`, paged.params(map[string]interface{}{"cuuid": cuuid, "prefix": query.Prefix}))
 
 		if err != nil {
 			return nil, err
 		}
 
-		var joined []model.Player
+		joined := []model.Player{}
+		var keys []interface{}
+		var uuids []string
 		for result.Next() {
 			record := result.Record()
 			if value, ok := record.Get("player"); ok {
@@ -155,7 +252,10 @@ func (env Env) listJoined(cuuid string) ([]model.Player, error) {
 					Money: props["money"].(float64),
 					Risk:  props["risk"].(int64),
 				}
+				key, _ := record.Get("key")
 				joined = append(joined, player)
+				keys = append(keys, key)
+				uuids = append(uuids, player.Uuid)
 			}
 		}
 
@@ -163,175 +263,266 @@ func (env Env) listJoined(cuuid string) ([]model.Player, error) {
 			return nil, err
 		}
 
-		return joined, nil
+		kept, next := paged.cut(keys, uuids)
+		return model.Page{Items: joined[:kept], NextCursor: next}, nil
 	})
 
 	if err != nil {
-		return nil, err
+		return model.Page{}, err
 	}
 
-	return people.([]model.Player), nil
+	return people.(model.Page), nil
 }
 
-// func (env Env) listModels(suuid string) (map[string]model.WinByMethod, error) {
-func (env Env) listModels(suuid string) (map[string]map[string]float64, error) {
+// one page of a space's current models, by player name
+func (env Env) listModels(suuid string, query model.ListQuery) (model.Page, error) {
+	paged, err := page(query, "player", "name")
+	if err != nil {
+		return model.Page{}, err
+	}
 
-	session := env.Driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	session := env.session("listModels", neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close()
 
 	people, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
 		result, err := tx.Run(`
 			MATCH (player:Player)-->(model:Model)-->(s:Space {uuid: $suuid})
-			RETURN player, model
-			`, map[string]interface{}{"suuid": suuid})
+			WHERE player.name STARTS WITH $prefix
+			WITH player, model, `+paged.key+` AS key
+			`+paged.window("player", "model")+`
+			RETURN player, model, key
+			`, paged.params(map[string]interface{}{"suuid": suuid, "prefix": query.Prefix}))
 
 		if err != nil {
 			return nil, err
 		}
 
-		var modelMap = make(map[string]map[string]float64)
+		models := []model.PlayerModel{}
+		var keys []interface{}
+		var uuids []string
 		for result.Next() {
 			record := result.Record()
-			if value, ok := record.Get("player"); ok {
-				node := value.(neo4j.Node)
-				name := node.Props["name"]
-				if val, err := record.Get("model"); err {
-					modelNode := val.(neo4j.Node)
-					props := modelNode.Props
-					model := make(map[string]float64)
-
-					for str, val := range props {
-						model[str] = val.(float64)
-					}
-
-					modelMap[name.(string)] = model
-				}
-			}
+			value, _ := record.Get("player")
+			m, _ := record.Get("model")
+			key, _ := record.Get("key")
+			props := value.(neo4j.Node).Props
+			models = append(models, model.PlayerModel{
+				Name:  props["name"].(string),
+				Uuid:  props["uuid"].(string),
+				Model: assertProps(m.(neo4j.Node).Props),
+			})
+			keys = append(keys, key)
+			uuids = append(uuids, props["uuid"].(string))
 		}
 
 		if err = result.Err(); err != nil {
 			return nil, err
 		}
 
-		return modelMap, nil
+		kept, next := paged.cut(keys, uuids)
+		return model.Page{Items: models[:kept], NextCursor: next}, nil
 	})
 
 	if err != nil {
-		return nil, err
+		return model.Page{}, err
 	}
 
-	return people.(map[string]map[string]float64), nil
+	return people.(model.Page), nil
 }
 
-func (env Env) listPayouts(suuid string) (map[string]map[string]float64, error) {
+// one page of a space's stored payouts, by player name, along with the
+// versions that tell whether they are stale
+func (env Env) listPayouts(suuid string, query model.ListQuery) (model.PayoutsPage, error) {
+	paged, err := page(query, "player", "name")
+	if err != nil {
+		return model.PayoutsPage{}, err
+	}
 
-	session := env.Driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	session := env.session("listPayouts", neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close()
 
 	payouts, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
-		result, err := tx.Run(`
-			MATCH (player:Player)<--(payout:Payout)<--(s:Space {uuid: $suuid})
-			RETURN player, payout
+		versions, err := tx.Run(`
+			MATCH (s:Space {uuid: $suuid})
+			RETURN coalesce(s.models_version, 0) AS models_version,
+				coalesce(s.payouts_version, 0) AS payouts_version
 			`, map[string]interface{}{"suuid": suuid})
 
 		if err != nil {
 			return nil, err
 		}
 
-		var payoutMap = make(map[string]map[string]float64)
-		for result.Next() {
-			record := result.Record()
-			if value, ok := record.Get("player"); ok {
-				node := value.(neo4j.Node)
-				name := node.Props["name"]
-				if val, err := record.Get("payout"); err {
-					modelNode := val.(neo4j.Node)
-					props := modelNode.Props
-					payout := make(map[string]float64)
+		var listed model.PayoutsPage
+		if versions.Next() {
+			record := versions.Record()
+			modelsVersion, _ := record.Get("models_version")
+			payoutsVersion, _ := record.Get("payouts_version")
+			listed.ModelsVersion = modelsVersion.(int64)
+			listed.PayoutsVersion = payoutsVersion.(int64)
+		}
+		if err = versions.Err(); err != nil {
+			return nil, err
+		}
 
-					for str, val := range props {
-						payout[str] = val.(float64)
-					}
+		result, err := tx.Run(`
+			MATCH (player:Player)<-[:FOR]-(payout:Payout)<--(s:Space {uuid: $suuid})
+			WHERE player.name STARTS WITH $prefix
+			WITH player, payout, `+paged.key+` AS key
+			`+paged.window("player", "payout")+`
+			OPTIONAL MATCH (payout)-[:FROM]->(rev:ModelRevision)
+			RETURN player, payout, rev.version AS source, key
+			`+paged.order("player")+`
+			`, paged.params(map[string]interface{}{"suuid": suuid, "prefix": query.Prefix}))
 
-					payoutMap[name.(string)] = payout
-				}
+		if err != nil {
+			return nil, err
+		}
+
+		rows := []model.PlayerPayout{}
+		var keys []interface{}
+		var uuids []string
+		for result.Next() {
+			record := result.Record()
+			value, _ := record.Get("player")
+			p, _ := record.Get("payout")
+			key, _ := record.Get("key")
+			props := value.(neo4j.Node).Props
+			row := model.PlayerPayout{
+				Name:   props["name"].(string),
+				Uuid:   props["uuid"].(string),
+				Payout: assertProps(p.(neo4j.Node).Props),
 			}
+			if source, ok := record.Get("source"); ok && source != nil {
+				row.Version = source.(int64)
+			}
+			rows = append(rows, row)
+			keys = append(keys, key)
+			uuids = append(uuids, row.Uuid)
 		}
 
 		if err = result.Err(); err != nil {
 			return nil, err
 		}
 
-		return payoutMap, nil
+		kept, next := paged.cut(keys, uuids)
+		listed.Items, listed.NextCursor = rows[:kept], next
+		listed.Stale = listed.ModelsVersion != listed.PayoutsVersion
+		return listed, nil
 	})
 
 	if err != nil {
-		return nil, err
+		return model.PayoutsPage{}, err
 	}
 
-	return payouts.(map[string]map[string]float64), nil
+	return payouts.(model.PayoutsPage), nil
 }
 
-func (env Env) submitModel(puuid string, suuid string, json map[string]float64) (string, error) {
-	session := env.Driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+// submit a new revision if the player's current one is at an expected
+// version, or unconditionally when none is; returns the new revision's version
+func (env Env) submitModel(puuid string, suuid string, json map[string]float64, expected []int64) (int64, error) {
+	session := env.session("submitModel", neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
 	defer session.Close()
 
 	query := formatProps(json, postModelQuery)
+	params := map[string]interface{}{
+		"puuid": puuid,
+		"suuid": suuid,
+	}
 
-	_, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
-		result, err := tx.Run(query, map[string]interface{}{
-			"puuid": puuid,
-			"suuid": suuid,
-		})
-
+	version, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		locked, err := tx.Run(lockModelQuery, params)
 		if err != nil {
 			return nil, err
 		}
+		record, err := locked.Single()
+		if err != nil {
+			return nil, errors.New("player is not in the space's circle")
+		}
+		current, _ := record.Get("current")
+		if !matches(current.(int64), expected) {
+			return nil, versionConflict{current: current.(int64), expected: expected} // rolls back the lock's bump
+		}
 
-		return result.Collect() // Collects and commits
+		result, err := tx.Run(query, params)
+		if err != nil {
+			return nil, err
+		}
+		record, err = result.Single()
+		if err != nil {
+			return nil, err
+		}
+		version, _ := record.Get("version")
+		return version, nil
 	})
 
 	if err != nil {
-		panic(err)
+		return 0, err
 	}
 
-	return "Model submitted.", nil
+	return version.(int64), nil
 }
 
+// write payouts computed from a snapshot in one transaction, so readers
+// never see some players' new payouts next to others' old ones
 func (env Env) postPayouts(
 	suuid string,
-	payouts map[string]map[string]float64) (string, error) {
+	payouts map[string]map[string]float64,
+	snapshot model.Snapshot) (string, error) {
 
-	session := env.Driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	session := env.session("postPayouts", neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
 	defer session.Close()
 
-	for name, payout := range payouts {
+	posted, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		// claiming the version first also locks the space, so a slower
+		// recalc from an older snapshot finds it taken and writes nothing
+		claimed, err := tx.Run(`
+			MATCH (s:Space {uuid: $suuid})
+			WHERE coalesce(s.payouts_version, 0) <= $version
+			SET s.payouts_version = $version
+			RETURN count(s) AS claimed
+		`, map[string]interface{}{"suuid": suuid, "version": snapshot.Version})
 
-		query := formatProps(payout, postPayoutQuery)
+		if err != nil {
+			return nil, err
+		}
+		record, err := claimed.Single()
+		if err != nil {
+			return nil, err
+		}
+		if count, _ := record.Get("claimed"); count.(int64) == 0 {
+			return false, nil
+		}
 
-		_, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
-			result, err := tx.Run(query, map[string]interface{}{
+		for name, payout := range payouts {
+			result, err := tx.Run(formatProps(payout, postPayoutQuery), map[string]interface{}{
 				"name":  name,
 				"suuid": suuid,
+				"ruuid": snapshot.Revisions[name],
 			})
 
 			if err != nil {
 				return nil, err
 			}
+			if _, err = result.Consume(); err != nil {
+				return nil, err
+			}
+		}
 
-			return result.Collect() // Collects and commits
-		})
+		return true, nil
+	})
 
-		if err != nil {
-			panic(err)
-		}
+	if err != nil {
+		return "", err
+	}
+	if !posted.(bool) {
+		return "", errSuperseded
 	}
 
 	return "Payouts posted.", nil
 }
 
 func (env Env) addRandom(cuuid string) (string, error) {
-	session := env.Driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	session := env.session("addRandom", neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
 	defer session.Close()
 
 	_, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
@@ -351,14 +542,14 @@ func (env Env) addRandom(cuuid string) (string, error) {
 	})
 
 	if err != nil {
-		panic(err)
+		return "", err
 	}
 
 	return "Player joined Circle.", nil
 }
 
 func (env Env) join(puuid string, cuuid string) (string, error) {
-	session := env.Driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	session := env.session("join", neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
 	defer session.Close()
 
 	_, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
@@ -377,14 +568,14 @@ func (env Env) join(puuid string, cuuid string) (string, error) {
 	})
 
 	if err != nil {
-		panic(err)
+		return "", err
 	}
 
 	return "Player joined Circle.", nil
 }
 
 func (env Env) leave(puuid string, cuuid string) (string, error) {
-	session := env.Driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	session := env.session("leave", neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
 	defer session.Close()
 
 	_, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
@@ -401,21 +592,36 @@ func (env Env) leave(puuid string, cuuid string) (string, error) {
 	})
 
 	if err != nil {
-		panic(err)
+		return "", err
 	}
 
 	return "Player left Circle.", nil
 }
 
 func (env Env) deleteModel(puuid string, suuid string) (string, error) {
-	session := env.Driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	session := env.session("deleteModel", neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
 	defer session.Close()
 
 	_, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
 		result, err := tx.Run(`
-			MATCH (p:Player {uuid: $puuid})--(n)--(s:Space {uuid: $suuid})
-			WHERE n:Model OR n:Payout
-			DETACH DELETE n
+			MATCH (p:Player {uuid: $puuid}), (s:Space {uuid: $suuid})
+			OPTIONAL MATCH (p)-[:SETS]->(old:ModelRevision)-[:FOR]->(s)
+			WITH p, s, coalesce(max(old.version), 0) AS latest
+			OPTIONAL MATCH (p)-[:SETS]->(prev:Model)-[:FOR]->(s)
+			`+adoptLegacyModel+`
+			REMOVE prev:Model
+			SET prev.current = false, prev.retired = timestamp()
+			WITH DISTINCT p, s, prev IS NOT NULL AS deleted
+			FOREACH (bumped IN CASE WHEN deleted THEN [s] ELSE [] END |
+				SET bumped.models_version = coalesce(bumped.models_version, 0) + 1)
+			WITH p, s
+			OPTIONAL MATCH (p)-[e:ESCROWED]->(s)
+			WHERE e.settled IS NULL
+			SET p.money = p.money + coalesce(e.amount, 0.0)
+			DELETE e
+			WITH DISTINCT p, s
+			OPTIONAL MATCH (s)-[:SETS]->(payout:Payout)-[:FOR]->(p)
+			DETACH DELETE payout
 		`, map[string]interface{}{"puuid": puuid, "suuid": suuid})
 
 		if err != nil {
@@ -426,14 +632,14 @@ func (env Env) deleteModel(puuid string, suuid string) (string, error) {
 	})
 
 	if err != nil {
-		panic(err)
+		return "", err
 	}
 
 	return "Model deleted.", nil
 }
 
 func (env Env) mapModels(suuid string) (map[string]map[string]float64, error) {
-	session := env.Driver.NewSession(neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	session := env.session("mapModels", neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
 	defer session.Close()
 
 	people, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
@@ -454,14 +660,93 @@ func (env Env) mapModels(suuid string) (map[string]map[string]float64, error) {
 				name := node.Props["name"]
 				if val, err := record.Get("model"); err {
 					modelNode := val.(neo4j.Node)
-					props := modelNode.Props
-					model := make(map[string]float64)
+					modelMap[name.(string)] = assertProps(modelNode.Props)
+				}
+			}
+		}
+
+		if err = result.Err(); err != nil {
+			return nil, err
+		}
+
+		return modelMap, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
 
-					for str, val := range props {
-						model[str] = val.(float64)
-					}
+	return people.(map[string]map[string]float64), nil
+}
+
+// returns every revision a player has submitted to a space, newest first
+func (env Env) listRevisions(puuid string, suuid string) ([]model.ModelRevision, error) {
+	session := env.session("listRevisions", neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	revisions, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			MATCH (p:Player {uuid: $puuid})-[:SETS]->(rev:ModelRevision)-[:FOR]->(s:Space {uuid: $suuid})
+			RETURN rev
+			ORDER BY rev.created DESC
+			`, map[string]interface{}{"puuid": puuid, "suuid": suuid})
+
+		if err != nil {
+			return nil, err
+		}
+
+		var history []model.ModelRevision
+		for result.Next() {
+			record := result.Record()
+			if value, ok := record.Get("rev"); ok {
+				node := value.(neo4j.Node)
+				history = append(history, parseRevision(node.Props))
+			}
+		}
+
+		if err = result.Err(); err != nil {
+			return nil, err
+		}
+
+		return history, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions.([]model.ModelRevision), nil
+}
+
+// maps the revision each player had in force at asOf (ms since epoch)
+func (env Env) mapModelsAsOf(suuid string, asOf int64) (map[string]map[string]float64, error) {
+	session := env.session("mapModelsAsOf", neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	people, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			MATCH (player:Player)-[:SETS]->(rev)-[:FOR]->(s:Space {uuid: $suuid})
+			WHERE (rev:Model OR rev:ModelRevision)
+			AND coalesce(rev.created, 0) <= $asof
+			AND (rev.retired IS NULL OR rev.retired > $asof)
+			WITH player, rev ORDER BY rev.created DESC
+			WITH player, collect(rev)[0] AS model
+			RETURN player, model
+			`, map[string]interface{}{"suuid": suuid, "asof": asOf})
+
+		if err != nil {
+			return nil, err
+		}
 
-					modelMap[name.(string)] = model
+		var modelMap = make(map[string]map[string]float64)
+		for result.Next() {
+			record := result.Record()
+			if value, ok := record.Get("player"); ok {
+				node := value.(neo4j.Node)
+				name := node.Props["name"]
+				if val, ok := record.Get("model"); ok {
+					modelNode := val.(neo4j.Node)
+					modelMap[name.(string)] = assertProps(modelNode.Props)
 				}
 			}
 		}
@@ -479,3 +764,1131 @@ func (env Env) mapModels(suuid string) (map[string]map[string]float64, error) {
 
 	return people.(map[string]map[string]float64), nil
 }
+
+func (env Env) createWebhook(cuuid string, url string, events []string, secret string) (model.Webhook, error) {
+	session := env.session("createWebhook", neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	record, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			MATCH (c:Circle {uuid: $cuuid})
+			CREATE (c)-[:NOTIFIES]->(w:Webhook {
+				uuid: randomUUID(), url: $url, events: $events, secret: $secret, created: timestamp()
+			})
+			RETURN w
+		`, map[string]interface{}{"cuuid": cuuid, "url": url, "events": events, "secret": secret})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return result.Single()
+	})
+
+	if err != nil {
+		return model.Webhook{}, err
+	}
+
+	value, _ := record.(*neo4j.Record).Get("w")
+	hook := webhookProps(value.(neo4j.Node).Props)
+	hook.Secret = secret
+	return hook, nil
+}
+
+func (env Env) listWebhooks(cuuid string) ([]model.Webhook, error) {
+	session := env.session("listWebhooks", neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	hooks, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			MATCH (c:Circle {uuid: $cuuid})-[:NOTIFIES]->(w:Webhook)
+			RETURN w
+			ORDER BY w.created
+		`, map[string]interface{}{"cuuid": cuuid})
+
+		if err != nil {
+			return nil, err
+		}
+
+		var webhooks []model.Webhook
+		for result.Next() {
+			if value, ok := result.Record().Get("w"); ok {
+				webhooks = append(webhooks, webhookProps(value.(neo4j.Node).Props))
+			}
+		}
+
+		if err = result.Err(); err != nil {
+			return nil, err
+		}
+
+		return webhooks, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return hooks.([]model.Webhook), nil
+}
+
+func (env Env) deleteWebhook(wuuid string) (string, error) {
+	session := env.session("deleteWebhook", neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	_, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			MATCH (w:Webhook {uuid: $wuuid})
+			OPTIONAL MATCH (d:Delivery)-[:TO]->(w)
+			DETACH DELETE d, w
+		`, map[string]interface{}{"wuuid": wuuid})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return result.Collect() // Collects and commits
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return "Webhook deleted.", nil
+}
+
+// queue a delivery for every webhook on the circle that topic names,
+// topic being either the circle's uuid or one of its spaces'
+func (env Env) enqueueDeliveries(topic string, event string, payload string) error {
+	session := env.session("enqueueDeliveries", neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	_, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			MATCH (c:Circle)-[:NOTIFIES]->(w:Webhook)
+			WHERE (c.uuid = $topic OR (c)-->(:Space {uuid: $topic}))
+			AND (size(w.events) = 0 OR $event IN w.events)
+			CREATE (d:Delivery {
+				uuid: randomUUID(), event: $event, payload: $payload, status: 'pending',
+				attempts: 0, created: timestamp(), next_attempt: timestamp()
+			})-[:TO]->(w)
+		`, map[string]interface{}{"topic": topic, "event": event, "payload": payload})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return result.Collect() // Collects and commits
+	})
+
+	return err
+}
+
+// claim up to limit pending deliveries that are due, pushing their next
+// attempt out by lease so other instances leave them alone while sending
+func (env Env) dueDeliveries(limit int, lease time.Duration) ([]webhook.Job, error) {
+	session := env.session("dueDeliveries", neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	jobs, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			MATCH (d:Delivery {status: 'pending'})-[:TO]->(w:Webhook)
+			WHERE d.next_attempt <= timestamp()
+			WITH d, w ORDER BY d.next_attempt LIMIT $limit
+			SET d.next_attempt = timestamp() + $lease
+			RETURN d, w
+		`, map[string]interface{}{"limit": limit, "lease": lease.Milliseconds()})
+
+		if err != nil {
+			return nil, err
+		}
+
+		var due []webhook.Job
+		for result.Next() {
+			record := result.Record()
+			d, _ := record.Get("d")
+			w, _ := record.Get("w")
+			delivery := d.(neo4j.Node).Props
+			hook := w.(neo4j.Node).Props
+			due = append(due, webhook.Job{
+				Delivery: delivery["uuid"].(string),
+				URL:      hook["url"].(string),
+				Secret:   hook["secret"].(string),
+				Event:    delivery["event"].(string),
+				Payload:  delivery["payload"].(string),
+				Attempts: int(delivery["attempts"].(int64)),
+			})
+		}
+
+		if err = result.Err(); err != nil {
+			return nil, err
+		}
+
+		return due, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs.([]webhook.Job), nil
+}
+
+func (env Env) recordDelivery(duuid string, status string, code int, failure string, next time.Time) error {
+	session := env.session("recordDelivery", neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	_, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			MATCH (d:Delivery {uuid: $duuid})
+			SET d.attempts = d.attempts + 1,
+				d.status = $status,
+				d.last_status = $code,
+				d.last_error = $failure,
+				d.next_attempt = $next,
+				d.delivered = CASE WHEN $status = 'delivered' THEN timestamp() ELSE null END
+		`, map[string]interface{}{
+			"duuid":   duuid,
+			"status":  status,
+			"code":    code,
+			"failure": failure,
+			"next":    next.UnixMilli(),
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return result.Collect() // Collects and commits
+	})
+
+	return err
+}
+
+// delivery log for a webhook, newest first
+func (env Env) listDeliveries(wuuid string) ([]model.Delivery, error) {
+	session := env.session("listDeliveries", neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	log, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			MATCH (d:Delivery)-[:TO]->(w:Webhook {uuid: $wuuid})
+			RETURN d
+			ORDER BY d.created DESC
+			LIMIT 100
+		`, map[string]interface{}{"wuuid": wuuid})
+
+		if err != nil {
+			return nil, err
+		}
+
+		var deliveries []model.Delivery
+		for result.Next() {
+			if value, ok := result.Record().Get("d"); ok {
+				props := value.(neo4j.Node).Props
+				delivery := model.Delivery{
+					Uuid:        props["uuid"].(string),
+					Event:       props["event"].(string),
+					Status:      props["status"].(string),
+					Attempts:    props["attempts"].(int64),
+					Created:     props["created"].(int64),
+					NextAttempt: props["next_attempt"].(int64),
+				}
+				if code, ok := props["last_status"].(int64); ok {
+					delivery.LastStatus = code
+				}
+				if failure, ok := props["last_error"].(string); ok {
+					delivery.LastError = failure
+				}
+				if delivered, ok := props["delivered"].(int64); ok {
+					delivery.Delivered = delivered
+				}
+				deliveries = append(deliveries, delivery)
+			}
+		}
+
+		if err = result.Err(); err != nil {
+			return nil, err
+		}
+
+		return deliveries, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return log.([]model.Delivery), nil
+}
+
+// resolved spaces in a circle whose resolution time falls within [from, to]
+func (env Env) listResolved(cuuid string, from int64, to int64) ([]score.Outcome, error) {
+	session := env.session("listResolved", neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	outcomes, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			MATCH (c:Circle {uuid: $cuuid})-->(s:Space)
+			WHERE s.resolved IS NOT NULL AND NOT coalesce(s.void, false)
+			AND coalesce(s.resolved, 0) >= $from AND coalesce(s.resolved, 0) <= $to
+			MATCH (player:Player)-[:SETS]->(model:Model)-[:FOR]->(s)
+			OPTIONAL MATCH (player)-[e:ESCROWED]->(s)
+			RETURN s, player, model, e.paid - e.amount AS won
+		`, map[string]interface{}{"cuuid": cuuid, "from": from, "to": to})
+
+		if err != nil {
+			return nil, err
+		}
+
+		var resolved []score.Outcome
+		index := make(map[string]int)
+		for result.Next() {
+			record := result.Record()
+			s, _ := record.Get("s")
+			p, _ := record.Get("player")
+			m, _ := record.Get("model")
+
+			props := s.(neo4j.Node).Props
+			suuid := props["uuid"].(string)
+			i, ok := index[suuid]
+			if !ok {
+				i = len(resolved)
+				index[suuid] = i
+				var space model.Space
+				resolveProps(&space, props)
+				resolved = append(resolved, score.Outcome{
+					Space:    suuid,
+					Weights:  space.Weights,
+					Fields:   assertArray(props["fields"].([]interface{})),
+					Models:   make(map[string]map[string]float64),
+					Winnings: make(map[string]float64),
+				})
+			}
+
+			name := p.(neo4j.Node).Props["name"].(string)
+			resolved[i].Models[name] = assertProps(m.(neo4j.Node).Props)
+			// what settling paid out, stored payouts may predate the last model
+			if won, ok := record.Get("won"); ok && won != nil {
+				resolved[i].Winnings[name], _ = won.(float64)
+			}
+		}
+
+		if err = result.Err(); err != nil {
+			return nil, err
+		}
+
+		return resolved, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return outcomes.([]score.Outcome), nil
+}
+
+func (env Env) circleOf(suuid string) (string, error) {
+	session := env.session("circleOf", neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	cuuid, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			MATCH (c:Circle)-->(s:Space {uuid: $suuid})
+			RETURN c.uuid AS cuuid
+		`, map[string]interface{}{"suuid": suuid})
+
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := result.Single()
+		if err != nil {
+			return nil, err
+		}
+
+		value, _ := record.Get("cuuid")
+		return value, nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return cuuid.(string), nil
+}
+
+func (env Env) createSpace(cuuid string, space model.Space) (model.Space, error) {
+	session := env.session("createSpace", neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	suuid, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			MATCH (c:Circle {uuid: $cuuid})
+			CREATE (c)-[:SPAWNED]->(s:Space {
+				uuid: randomUUID(), name: $name, description: $description, kind: $kind,
+				fields: $fields, pattern: $pattern, stake: $stake,
+				min: $min, max: $max, step: $step, created: timestamp()
+			})
+			WITH c, s
+			CALL {
+				WITH c, s
+				MATCH (c)-->(parent:Space {uuid: $parent})
+				WHERE $condition IN parent.fields AND parent.resolved IS NULL
+				CREATE (s)-[:DEPENDS_ON {field: $condition}]->(parent)
+				RETURN count(parent) AS linked
+			}
+			WITH s, linked
+			WHERE $parent = '' OR linked = 1
+			RETURN s.uuid AS suuid
+		`, map[string]interface{}{
+			"cuuid":       cuuid,
+			"parent":      space.Parent,
+			"condition":   space.Condition,
+			"name":        space.Name,
+			"description": space.Description,
+			"kind":        space.Kind,
+			"fields":      space.Fields,
+			"pattern":     space.Pattern,
+			"stake":       space.Stake,
+			"min":         space.Min,
+			"max":         space.Max,
+			"step":        space.Step,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := result.Single()
+		if err != nil {
+			return nil, err
+		}
+
+		value, _ := record.Get("suuid")
+		return value, nil
+	})
+
+	if err != nil {
+		return model.Space{}, err
+	}
+
+	space.Uuid = suuid.(string)
+	return space, nil
+}
+
+// resolve a space to a weighted mix of fields, paying each escrowed player
+// their stake plus winnings -> name: amount, then voiding every conditional
+// child whose condition did not come true with certainty
+func (env Env) settleSpace(
+	suuid string,
+	weights map[string]float64,
+	winnings map[string]float64) (string, error) {
+
+	session := env.session("settleSpace", neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	var single interface{} // resolution is only set when one field took everything
+	var fields, certain []string
+	var mix []float64
+	for field, weight := range weights {
+		fields = append(fields, field)
+		mix = append(mix, weight)
+		if weight == 1 {
+			single = field
+			certain = append(certain, field)
+		}
+	}
+
+	_, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		claimed, err := tx.Run(`
+			MATCH (s:Space {uuid: $suuid})
+			WHERE s.resolved IS NULL
+			SET s.resolution = $single, s.resolution_fields = $fields,
+				s.resolution_weights = $mix, s.resolved = timestamp()
+			RETURN count(s) AS claimed
+		`, map[string]interface{}{
+			"suuid":  suuid,
+			"single": single,
+			"fields": fields,
+			"mix":    mix,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+		record, err := claimed.Single()
+		if err != nil {
+			return nil, err
+		}
+		// a concurrent resolve got here first, leave its children alone
+		if count, _ := record.Get("claimed"); count.(int64) == 0 {
+			return nil, conflict{errors.New("space is already resolved")}
+		}
+
+		settled, err := tx.Run(`
+			MATCH (p:Player)-[e:ESCROWED]->(s:Space {uuid: $suuid})
+			WHERE e.settled IS NULL
+			WITH e, p, e.amount + coalesce($winnings[p.name], 0.0) AS paid
+			SET p.money = p.money + paid, e.settled = timestamp(), e.paid = paid
+		`, map[string]interface{}{"suuid": suuid, "winnings": winnings})
+
+		if err != nil {
+			return nil, err
+		}
+		if _, err = settled.Consume(); err != nil {
+			return nil, err
+		}
+
+		children, err := tx.Run(`
+			MATCH (child:Space)-[d:DEPENDS_ON]->(:Space {uuid: $suuid})
+			WHERE NOT d.field IN $certain
+			RETURN collect(child.uuid) AS roots
+		`, map[string]interface{}{"suuid": suuid, "certain": certain})
+
+		if err != nil {
+			return nil, err
+		}
+
+		record, err = children.Single()
+		if err != nil {
+			return nil, err
+		}
+
+		roots, _ := record.Get("roots")
+		result, err := tx.Run(voidQuery, map[string]interface{}{"roots": roots})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return result.Collect() // Collects and commits
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return "Space resolved.", nil
+}
+
+// void a space and every space conditional on it, refunding all escrow
+func (env Env) voidSpace(suuid string) (string, error) {
+	session := env.session("voidSpace", neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	_, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(voidQuery, map[string]interface{}{"roots": []string{suuid}})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return result.Collect() // Collects and commits
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return "Space voided.", nil
+}
+
+// read current models together with their revisions and the models
+// version they represent
+func (env Env) snapshotModels(suuid string) (model.Snapshot, error) {
+	session := env.session("snapshotModels", neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	snap, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			MATCH (s:Space {uuid: $suuid})
+			OPTIONAL MATCH (player:Player)-->(model:Model)-->(s)
+			RETURN coalesce(s.models_version, 0) AS version, player, model
+			`, map[string]interface{}{"suuid": suuid})
+
+		if err != nil {
+			return nil, err
+		}
+
+		taken := model.Snapshot{
+			Models:    make(map[string]map[string]float64),
+			Revisions: make(map[string]string),
+			Versions:  make(map[string]int64),
+		}
+		for result.Next() {
+			record := result.Record()
+			version, _ := record.Get("version")
+			taken.Version = version.(int64)
+			if value, ok := record.Get("player"); ok && value != nil {
+				name := value.(neo4j.Node).Props["name"].(string)
+				m, _ := record.Get("model")
+				props := m.(neo4j.Node).Props
+				taken.Models[name] = assertProps(props)
+				if ruuid, ok := props["uuid"].(string); ok {
+					taken.Revisions[name] = ruuid
+				}
+				if version, ok := props["version"].(int64); ok {
+					taken.Versions[name] = version
+				}
+			}
+		}
+
+		if err = result.Err(); err != nil {
+			return nil, err
+		}
+
+		return taken, nil
+	})
+
+	if err != nil {
+		return model.Snapshot{}, err
+	}
+
+	return snap.(model.Snapshot), nil
+}
+
+// the revisions the posted payouts were computed from, with the payouts
+// version as Version; stale when models changed since, or when a payout
+// predates FROM links and its revision is unknown
+func (env Env) postedModels(suuid string) (model.Snapshot, bool, error) {
+	session := env.session("postedModels", neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	type posted struct {
+		snapshot model.Snapshot
+		stale    bool
+	}
+
+	read, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			MATCH (s:Space {uuid: $suuid})
+			OPTIONAL MATCH (s)-[:SETS]->(payout:Payout)-[:FOR]->(player:Player)
+			OPTIONAL MATCH (payout)-[:FROM]->(rev:ModelRevision)
+			RETURN coalesce(s.payouts_version, 0) AS version,
+				coalesce(s.payouts_version, 0) < coalesce(s.models_version, 0) AS changed,
+				player, rev
+			`, map[string]interface{}{"suuid": suuid})
+
+		if err != nil {
+			return nil, err
+		}
+
+		read := posted{snapshot: model.Snapshot{
+			Models:    make(map[string]map[string]float64),
+			Revisions: make(map[string]string),
+			Versions:  make(map[string]int64),
+		}}
+		for result.Next() {
+			record := result.Record()
+			if version, ok := record.Get("version"); ok {
+				read.snapshot.Version, _ = version.(int64)
+			}
+			if changed, ok := record.Get("changed"); ok && changed == true {
+				read.stale = true
+			}
+			value, ok := record.Get("player")
+			if !ok || value == nil {
+				continue
+			}
+			name, _ := value.(neo4j.Node).Props["name"].(string)
+			rev, _ := record.Get("rev")
+			if rev == nil {
+				read.stale = true
+				continue
+			}
+			revision := parseRevision(rev.(neo4j.Node).Props)
+			read.snapshot.Models[name] = revision.Model
+			read.snapshot.Revisions[name] = revision.Uuid
+			read.snapshot.Versions[name] = revision.Version
+		}
+
+		if err = result.Err(); err != nil {
+			return nil, err
+		}
+
+		return read, nil
+	})
+
+	if err != nil {
+		return model.Snapshot{}, false, err
+	}
+
+	return read.(posted).snapshot, read.(posted).stale, nil
+}
+
+func (env Env) getPlayer(puuid string) (model.Player, error) {
+	session := env.session("getPlayer", neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	player, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			MATCH (player:Player {uuid: $puuid})
+			RETURN player
+		`, map[string]interface{}{"puuid": puuid})
+
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := result.Single()
+		if err != nil {
+			return nil, err
+		}
+
+		value, _ := record.Get("player")
+		props := value.(neo4j.Node).Props
+		return model.Player{
+			Name:  props["name"].(string),
+			Uuid:  props["uuid"].(string),
+			Money: props["money"].(float64),
+			Risk:  props["risk"].(int64),
+		}, nil
+	})
+
+	if err != nil {
+		return model.Player{}, err
+	}
+
+	return player.(model.Player), nil
+}
+
+// create a bot player playing strategy and join it to the circle
+func (env Env) createBot(cuuid string, strategy string, target string) (model.Bot, error) {
+	session := env.session("createBot", neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	created, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			MATCH (c:Circle {uuid: $cuuid})
+			WITH c, randomUUID() AS id
+			CREATE (p:Player:Bot {
+				uuid: id, name: 'bot-' + substring(id, 0, 8), money: $money, risk: 0,
+				strategy: $strategy, target: $target
+			})-[:JOINED]->(c)
+			RETURN p
+		`, map[string]interface{}{
+			"cuuid":    cuuid,
+			"money":    botMoney,
+			"strategy": strategy,
+			"target":   target,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := result.Single()
+		if err != nil {
+			return nil, err
+		}
+
+		value, _ := record.Get("p")
+		return botProps(value.(neo4j.Node).Props), nil
+	})
+
+	if err != nil {
+		return model.Bot{}, err
+	}
+
+	return created.(model.Bot), nil
+}
+
+func (env Env) listBots(cuuid string) ([]model.Bot, error) {
+	session := env.session("listBots", neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	bots, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			MATCH (p:Bot)-[:JOINED]->(c:Circle {uuid: $cuuid})
+			RETURN p
+		`, map[string]interface{}{"cuuid": cuuid})
+
+		if err != nil {
+			return nil, err
+		}
+
+		var joined []model.Bot
+		for result.Next() {
+			if value, ok := result.Record().Get("p"); ok {
+				joined = append(joined, botProps(value.(neo4j.Node).Props))
+			}
+		}
+
+		if err = result.Err(); err != nil {
+			return nil, err
+		}
+
+		return joined, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return bots.([]model.Bot), nil
+}
+
+func (env Env) schemaVersion() (int, error) {
+	session := env.session("schemaVersion", neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	version, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			OPTIONAL MATCH (schema:Schema {name: 'riverboat'})
+			RETURN coalesce(schema.version, 0) AS version
+		`, map[string]interface{}{})
+
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := result.Single()
+		if err != nil {
+			return nil, err
+		}
+
+		value, _ := record.Get("version")
+		return value, nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return int(version.(int64)), nil
+}
+
+// run one migration's statements, each in its own transaction, then record version
+func (env Env) migrate(version int, statements []string) error {
+	session := env.session("migrate", neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	for _, statement := range statements {
+		result, err := session.Run(statement, map[string]interface{}{})
+		if err != nil {
+			return err
+		}
+		if _, err = result.Consume(); err != nil {
+			return err
+		}
+	}
+
+	_, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			MERGE (schema:Schema {name: 'riverboat'})
+			SET schema.version = $version, schema.migrated = timestamp()
+		`, map[string]interface{}{"version": version})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return result.Collect() // Collects and commits
+	})
+
+	return err
+}
+
+func (env Env) seed(players int, money float64) (string, error) {
+	session := env.session("seed", neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	cuuid, err := session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			CREATE (c:Circle {uuid: randomUUID(), name: 'Demo Circle', spawned_by: 'seed', created: timestamp()})
+			WITH c
+			UNWIND range(1, $players) AS i
+			WITH c, randomUUID() AS id
+			CREATE (:Player {
+				uuid: id, name: 'player-' + substring(id, 0, 8), money: $money, risk: 0
+			})-[:JOINED]->(c)
+			WITH c, count(*) AS joined
+			CREATE (c)-[:SPAWNED]->(:Space {
+				uuid: randomUUID(), name: 'Demo Space', description: 'Seeded by riverboat seed',
+				kind: 'categorical', fields: ['yes', 'no'], pattern: 'waterfall', stake: 10.0,
+				min: 0.0, max: 0.0, step: 0.0, models_version: 0, payouts_version: 0,
+				created: timestamp()
+			})
+			RETURN c.uuid AS cuuid
+		`, map[string]interface{}{"players": players, "money": money})
+
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := result.Single()
+		if err != nil {
+			return nil, err
+		}
+
+		value, _ := record.Get("cuuid")
+		return value, nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return cuuid.(string), nil
+}
+
+// every node and relationship except the schema marker
+func (env Env) export() (model.Dump, error) {
+	session := env.session("export", neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	dump, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		dump := model.Dump{Nodes: []model.DumpNode{}, Relationships: []model.DumpRelationship{}}
+
+		nodes, err := tx.Run(`
+			MATCH (n) WHERE NOT n:Schema
+			RETURN elementId(n) AS id, labels(n) AS labels, properties(n) AS props
+		`, map[string]interface{}{})
+
+		if err != nil {
+			return nil, err
+		}
+
+		for nodes.Next() {
+			record := nodes.Record()
+			id, _ := record.Get("id")
+			labels, _ := record.Get("labels")
+			props, _ := record.Get("props")
+			tagged, err := typedProps(props.(map[string]interface{}))
+			if err != nil {
+				return nil, err
+			}
+			dump.Nodes = append(dump.Nodes, model.DumpNode{
+				ID:     id.(string),
+				Labels: assertArray(labels.([]interface{})),
+				Props:  tagged,
+			})
+		}
+
+		if err = nodes.Err(); err != nil {
+			return nil, err
+		}
+
+		rels, err := tx.Run(`
+			MATCH (a)-[r]->(b) WHERE NOT a:Schema AND NOT b:Schema
+			RETURN elementId(a) AS start, elementId(b) AS end, type(r) AS type, properties(r) AS props
+		`, map[string]interface{}{})
+
+		if err != nil {
+			return nil, err
+		}
+
+		for rels.Next() {
+			record := rels.Record()
+			start, _ := record.Get("start")
+			end, _ := record.Get("end")
+			kind, _ := record.Get("type")
+			props, _ := record.Get("props")
+			tagged, err := typedProps(props.(map[string]interface{}))
+			if err != nil {
+				return nil, err
+			}
+			dump.Relationships = append(dump.Relationships, model.DumpRelationship{
+				Start: start.(string),
+				End:   end.(string),
+				Type:  kind.(string),
+				Props: tagged,
+			})
+		}
+
+		if err = rels.Err(); err != nil {
+			return nil, err
+		}
+
+		return dump, nil
+	})
+
+	if err != nil {
+		return model.Dump{}, err
+	}
+
+	return dump.(model.Dump), nil
+}
+
+// recreate a dump in an empty database, nodes are matched up by their
+// exported id through a temporary :Imported label
+func (env Env) load(dump model.Dump) (string, error) {
+	nodes := make(map[string][]interface{}) // labels: rows
+	for _, node := range dump.Nodes {
+		props, err := untypedProps(node.Props)
+		if err != nil {
+			return "", fmt.Errorf("node %s: %w", node.ID, err)
+		}
+		labels := quoteNames(node.Labels)
+		nodes[labels] = append(nodes[labels], map[string]interface{}{"id": node.ID, "props": props})
+	}
+
+	rels := make(map[string][]interface{}) // type: rows
+	for _, rel := range dump.Relationships {
+		props, err := untypedProps(rel.Props)
+		if err != nil {
+			return "", fmt.Errorf("relationship %s-[%s]->%s: %w", rel.Start, rel.Type, rel.End, err)
+		}
+		kind := quoteNames([]string{rel.Type})
+		rels[kind] = append(rels[kind], map[string]interface{}{"start": rel.Start, "end": rel.End, "props": props})
+	}
+
+	// the whole file is checked before anything touches the database
+	session := env.session("load", neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close()
+
+	index, err := session.Run(`CREATE INDEX imported_id IF NOT EXISTS FOR (n:Imported) ON (n._import)`, nil)
+	if err == nil {
+		_, err = index.Consume()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	_, err = session.WriteTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			MATCH (n) WHERE NOT n:Schema
+			RETURN count(n) AS existing
+		`, map[string]interface{}{})
+
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := result.Single()
+		if err != nil {
+			return nil, err
+		}
+
+		if existing, _ := record.Get("existing"); existing.(int64) > 0 {
+			return nil, errors.New("database is not empty")
+		}
+
+		for labels, rows := range nodes {
+			result, err := tx.Run(`
+				UNWIND $rows AS row
+				CREATE (n`+labels+`:Imported)
+				SET n = row.props, n._import = row.id
+			`, map[string]interface{}{"rows": rows})
+
+			if err != nil {
+				return nil, err
+			}
+			if _, err = result.Consume(); err != nil {
+				return nil, err
+			}
+		}
+
+		for kind, rows := range rels {
+			result, err := tx.Run(`
+				UNWIND $rows AS row
+				MATCH (a:Imported {_import: row.start}), (b:Imported {_import: row.end})
+				CREATE (a)-[r`+kind+`]->(b)
+				SET r = row.props
+			`, map[string]interface{}{"rows": rows})
+
+			if err != nil {
+				return nil, err
+			}
+			if _, err = result.Consume(); err != nil {
+				return nil, err
+			}
+		}
+
+		result, err = tx.Run(`
+			MATCH (n:Imported)
+			REMOVE n:Imported, n._import
+		`, map[string]interface{}{})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return result.Collect() // Collects and commits
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Imported %d nodes and %d relationships.", len(dump.Nodes), len(dump.Relationships)), nil
+}
+
+// run a ledger check, returning the detail of every row it finds
+func (env Env) audit(query string) ([]string, error) {
+	session := env.session("audit", neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	found, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(query, map[string]interface{}{})
+
+		if err != nil {
+			return nil, err
+		}
+
+		var details []string
+		for result.Next() {
+			if detail, ok := result.Record().Get("detail"); ok && detail != nil {
+				details = append(details, detail.(string))
+			}
+		}
+
+		if err = result.Err(); err != nil {
+			return nil, err
+		}
+
+		return details, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return found.([]string), nil
+}
+
+func (env Env) totals() (model.Totals, error) {
+	session := env.session("totals", neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close()
+
+	totals, err := session.ReadTransaction(func(tx neo4j.Transaction) (interface{}, error) {
+		result, err := tx.Run(`
+			CALL {
+				MATCH (s:Space) WHERE s.resolved IS NULL
+				RETURN count(s) AS open_spaces
+			}
+			CALL {
+				MATCH (p:Player)-[:SETS]->(:Model)-[:FOR]->(s:Space) WHERE s.resolved IS NULL
+				RETURN count(DISTINCT p) AS active_players
+			}
+			CALL {
+				MATCH (:Player)-[e:ESCROWED]->(:Space) WHERE e.settled IS NULL
+				RETURN 0.0 + sum(e.amount) AS escrow // float even when empty
+			}
+			RETURN open_spaces, active_players, escrow
+		`, map[string]interface{}{})
+
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := result.Single()
+		if err != nil {
+			return nil, err
+		}
+
+		openSpaces, _ := record.Get("open_spaces")
+		activePlayers, _ := record.Get("active_players")
+		escrow, _ := record.Get("escrow")
+		return model.Totals{
+			OpenSpaces:    openSpaces.(int64),
+			ActivePlayers: activePlayers.(int64),
+			Escrow:        escrow.(float64),
+		}, nil
+	})
+
+	if err != nil {
+		return model.Totals{}, err
+	}
+
+	return totals.(model.Totals), nil
+}
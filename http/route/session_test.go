@@ -0,0 +1,166 @@
+package route
+
+import (
+	"errors"
+	"reflect"
+	"riverboat/model"
+	"testing"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// driver whose transactions answer each statement with the next scripted
+// record, recording what was run
+type script struct {
+	neo4j.Driver
+	replies []*neo4j.Record // nil for statements read with Consume or Collect
+	runs    []statement
+}
+
+type statement struct {
+	cypher string
+	params map[string]interface{}
+}
+
+func (s *script) NewSession(neo4j.SessionConfig) neo4j.Session {
+	return scriptSession{script: s}
+}
+
+type scriptSession struct {
+	neo4j.Session
+	script *script
+}
+
+func (s scriptSession) WriteTransaction(work neo4j.TransactionWork, _ ...func(*neo4j.TransactionConfig)) (interface{}, error) {
+	return work(scriptTx{script: s.script})
+}
+
+func (s scriptSession) Close() error {
+	return nil
+}
+
+type scriptTx struct {
+	neo4j.Transaction
+	script *script
+}
+
+func (tx scriptTx) Run(cypher string, params map[string]interface{}) (neo4j.Result, error) {
+	s := tx.script
+	s.runs = append(s.runs, statement{cypher, params})
+	if len(s.replies) == 0 {
+		return scriptResult{}, nil
+	}
+	reply := s.replies[0]
+	s.replies = s.replies[1:]
+	return scriptResult{record: reply}, nil
+}
+
+type scriptResult struct {
+	neo4j.Result
+	record *neo4j.Record
+}
+
+func (r scriptResult) Single() (*neo4j.Record, error) {
+	if r.record == nil {
+		return nil, errors.New("no record")
+	}
+	return r.record, nil
+}
+
+func (r scriptResult) Consume() (neo4j.ResultSummary, error) {
+	return nil, nil
+}
+
+func (r scriptResult) Collect() ([]*neo4j.Record, error) {
+	return nil, nil
+}
+
+func record(key string, value interface{}) *neo4j.Record {
+	return &neo4j.Record{Keys: []string{key}, Values: []interface{}{value}}
+}
+
+func TestNestSpaces(t *testing.T) {
+	spaces := []model.Space{
+		{Uuid: "root"},
+		{Uuid: "child", Parent: "root"},
+		{Uuid: "grandchild", Parent: "child"},
+		{Uuid: "sibling", Parent: "root"},
+		{Uuid: "orphan", Parent: "elsewhere"}, // parent not on this page
+	}
+
+	roots := nestSpaces(spaces)
+	if len(roots) != 2 || roots[0].Uuid != "root" || roots[1].Uuid != "orphan" {
+		t.Fatalf("roots %+v", roots)
+	}
+
+	children := roots[0].Children
+	if len(children) != 2 || children[0].Uuid != "child" || children[1].Uuid != "sibling" {
+		t.Fatalf("root's children %+v", children)
+	}
+	if grand := children[0].Children; len(grand) != 1 || grand[0].Uuid != "grandchild" {
+		t.Errorf("child's children %+v", grand)
+	}
+	if len(children[1].Children) != 0 || len(roots[1].Children) != 0 {
+		t.Errorf("leaves have children: %+v", roots)
+	}
+
+	if nestSpaces(nil) != nil {
+		t.Error("nothing nested into something")
+	}
+}
+
+// resolving voids every child conditional on a field that did not come
+// true with certainty, voidQuery then walks down from those children
+func TestSettleCascades(t *testing.T) {
+	cases := []struct {
+		weights map[string]float64
+		certain []string
+	}{
+		{map[string]float64{"x": 1, "y": 0}, []string{"x"}},
+		{map[string]float64{"x": 0.5, "y": 0.5}, nil}, // every child is voided
+	}
+	for _, c := range cases {
+		db := &script{replies: []*neo4j.Record{
+			record("claimed", int64(1)),
+			nil, // escrow settled
+			record("roots", []interface{}{"child"}),
+		}}
+		if _, err := (Env{Driver: db}).settleSpace("parent", c.weights, nil); err != nil {
+			t.Fatal(err)
+		}
+		if len(db.runs) != 4 {
+			t.Fatalf("ran %d statements, want 4", len(db.runs))
+		}
+
+		children, void := db.runs[2], db.runs[3]
+		if certain := children.params["certain"].([]string); !reflect.DeepEqual(certain, c.certain) {
+			t.Errorf("weights %v kept children on %v, want %v", c.weights, certain, c.certain)
+		}
+		if void.cypher != voidQuery || !reflect.DeepEqual(void.params["roots"], []interface{}{"child"}) {
+			t.Errorf("voided %v", void.params)
+		}
+	}
+}
+
+func TestSettleClaimedCascadesNothing(t *testing.T) {
+	db := &script{replies: []*neo4j.Record{record("claimed", int64(0))}}
+
+	_, err := (Env{Driver: db}).settleSpace("parent", map[string]float64{"x": 1}, nil)
+	if _, ok := err.(conflict); !ok {
+		t.Errorf("resolving twice gave %v", err)
+	}
+	if len(db.runs) != 1 {
+		t.Errorf("ran %d statements after losing the claim", len(db.runs))
+	}
+}
+
+func TestVoidCascades(t *testing.T) {
+	db := &script{}
+	if _, err := (Env{Driver: db}).voidSpace("parent"); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.runs) != 1 || db.runs[0].cypher != voidQuery ||
+		!reflect.DeepEqual(db.runs[0].params["roots"], []string{"parent"}) {
+		t.Errorf("ran %+v", db.runs)
+	}
+}
@@ -0,0 +1,87 @@
+package route
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"goyave.dev/goyave/v4"
+)
+
+// how often a comment line is sent to keep idle proxies from closing the
+// stream, well inside the default 10s server write timeout
+const keepAlive = 5 * time.Second
+
+func (h Handler) StreamCircle(response *goyave.Response, r *goyave.Request) {
+	h.stream(response, r, r.Params["cuuid"])
+}
+
+func (h Handler) StreamSpace(response *goyave.Response, r *goyave.Request) {
+	h.stream(response, r, r.Params["suuid"])
+}
+
+// serve topic as Server-Sent Events, replaying anything after Last-Event-ID
+// every write pushes the server write timeout back so an open stream is not
+// cut off; if it still ends, the browser's EventSource reconnects with
+// Last-Event-ID and picks up where it left off
+func (h Handler) stream(response *goyave.Response, r *goyave.Request, topic string) {
+	flusher, ok := response.GetWriter().(http.Flusher)
+	if h.Hub == nil || !ok {
+		response.String(http.StatusNotImplemented, "Error: Streaming unsupported.") // 501
+		return
+	}
+
+	missed, events, cancel := h.Hub.Subscribe(topic, r.Header().Get("Last-Event-ID"))
+	defer cancel()
+
+	response.Header().Set("Content-Type", "text/event-stream")
+	response.Header().Set("Cache-Control", "no-cache")
+	response.Header().Set("Connection", "keep-alive")
+	response.Status(http.StatusOK)
+	fmt.Fprint(response, "retry: 3000\n\n")
+
+	for _, event := range missed {
+		writeEvent(response, event.ID, event.Kind, event.Data)
+	}
+	extend(response.GetWriter())
+	flusher.Flush()
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	done := r.Request().Context().Done()
+	for {
+		select {
+		case <-done:
+			return
+		case <-h.Hub.Done():
+			return // shutting down, the client reconnects to another instance
+		case event := <-events:
+			writeEvent(response, event.ID, event.Kind, event.Data)
+			extend(response.GetWriter())
+			flusher.Flush()
+		case <-ticker.C:
+			fmt.Fprint(response, ": ping\n\n")
+			extend(response.GetWriter())
+			flusher.Flush()
+		}
+	}
+}
+
+// the write timeout is counted from when the request came in, move it to
+// two keep-alives from now; writers that can't are left to time out
+func extend(w io.Writer) {
+	if rw, ok := w.(http.ResponseWriter); ok {
+		http.NewResponseController(rw).SetWriteDeadline(time.Now().Add(2 * keepAlive))
+	}
+}
+
+func writeEvent(response *goyave.Response, id string, kind string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(response, "id: %s\nevent: %s\ndata: %s\n\n", id, kind, payload)
+}
@@ -0,0 +1,139 @@
+package route
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// tracks background goroutines so shutdown can wait for them to finish
+// a nil *Workers runs everything untracked, as the CLI commands do
+type Workers struct {
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	stop     chan struct{}
+	stopping bool
+	beats    map[string]time.Time
+}
+
+func NewWorkers() *Workers {
+	return &Workers{
+		stop:  make(chan struct{}),
+		beats: make(map[string]time.Time),
+	}
+}
+
+// run a long-lived loop until stop is closed by Drain, the loop should
+// Beat with the same name each time around so readiness can tell it's alive
+func (w *Workers) Go(name string, loop func(stop <-chan struct{})) {
+	if w == nil {
+		go loop(make(chan struct{}))
+		return
+	}
+	if w.add() {
+		w.Beat(name)
+		go func() {
+			defer w.wg.Done()
+			loop(w.stop)
+		}()
+	}
+}
+
+// run a one-off job in the background, dropped once draining has begun
+func (w *Workers) Spawn(job func()) {
+	if w == nil {
+		go job()
+		return
+	}
+	if w.add() {
+		go func() {
+			defer w.wg.Done()
+			job()
+		}()
+	}
+}
+
+// record that the named loop is still making progress
+func (w *Workers) Beat(name string) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.beats[name] = time.Now()
+}
+
+// error when draining or when a loop hasn't beaten within maxAge
+func (w *Workers) Check(maxAge time.Duration) error {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stopping {
+		return errors.New("shutting down")
+	}
+
+	var stale []string
+	for name, beat := range w.beats {
+		if since := time.Since(beat); since > maxAge {
+			stale = append(stale, fmt.Sprintf("%s idle for %s", name, since.Round(time.Second)))
+		}
+	}
+	if len(stale) > 0 {
+		sort.Strings(stale)
+		return fmt.Errorf("%v", stale)
+	}
+	return nil
+}
+
+// refuse new work, signal the loops and wait up to timeout for everything
+// already running to return
+func (w *Workers) Drain(timeout time.Duration) error {
+	if w == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	if !w.stopping {
+		w.stopping = true
+		close(w.stop)
+	}
+	w.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-time.After(timeout):
+		return errors.New("background workers still running after " + timeout.String())
+	}
+}
+
+// whether Drain has begun, after which Go and Spawn start nothing
+func (w *Workers) draining() bool {
+	if w == nil {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stopping
+}
+
+func (w *Workers) add() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopping {
+		return false
+	}
+	w.wg.Add(1)
+	return true
+}
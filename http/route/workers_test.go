@@ -0,0 +1,48 @@
+package route
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDrainWaitsForWorkers(t *testing.T) {
+	workers := NewWorkers()
+	release := make(chan struct{})
+	workers.Spawn(func() { <-release })
+	workers.Go("loop", func(stop <-chan struct{}) { <-stop })
+
+	drained := make(chan error)
+	go func() { drained <- workers.Drain(5 * time.Second) }()
+
+	select {
+	case err := <-drained:
+		t.Fatalf("drained with a job still running: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-drained; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDrainTimesOut(t *testing.T) {
+	workers := NewWorkers()
+	stuck := make(chan struct{})
+	defer close(stuck)
+	workers.Spawn(func() { <-stuck })
+
+	start := time.Now()
+	if err := workers.Drain(50 * time.Millisecond); err == nil {
+		t.Error("drained with a stuck worker")
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("gave up after %s", waited)
+	}
+
+	ran := false
+	workers.Spawn(func() { ran = true })
+	if !workers.draining() || ran {
+		t.Error("took new work while draining")
+	}
+}
@@ -0,0 +1,165 @@
+package score
+
+import (
+	"math"
+	"sort"
+)
+
+// number of equal-width reliability buckets over [0, 1]
+const buckets = 10
+
+// a resolved space and everything submitted to it -> name: { field: value, ... }
+// Weights is how much of the resolution went to each field, summing to 1
+// for exclusive kinds and 0 or 1 per field for multi-select; Winnings is
+// what settling paid each player beyond their stake -> name: amount
+type Outcome struct {
+	Space    string
+	Weights  map[string]float64
+	Fields   []string
+	Models   map[string]map[string]float64
+	Winnings map[string]float64
+}
+
+// forecasts that fell in [Lower, Upper) and how often they came true
+type Bucket struct {
+	Lower    float64 `json:"lower"`
+	Upper    float64 `json:"upper"`
+	Count    int     `json:"count"`
+	Forecast float64 `json:"forecast"` // mean forecast probability
+	Observed float64 `json:"observed"` // fraction that resolved true
+}
+
+type Standing struct {
+	Rank        int      `json:"rank"`
+	Player      string   `json:"player"`
+	Spaces      int      `json:"spaces"`
+	Winnings    float64  `json:"winnings"`
+	Brier       float64  `json:"brier"` // mean over spaces, 0 is perfect
+	Reliability []Bucket `json:"reliability"`
+}
+
+// rank players by cumulative winnings, breaking ties on calibration
+func Leaderboard(outcomes []Outcome) []Standing {
+
+	type tally struct {
+		spaces   int
+		winnings float64
+		brier    float64
+		count    [buckets]int
+		forecast [buckets]float64
+		observed [buckets]float64
+	}
+
+	tallies := make(map[string]*tally)
+	for _, outcome := range outcomes {
+		for name, model := range outcome.Models {
+			t, ok := tallies[name]
+			if !ok {
+				t = &tally{}
+				tallies[name] = t
+			}
+
+			t.spaces++
+			t.winnings += outcome.Winnings[name]
+			t.brier += Brier(model, outcome.Fields, outcome.Weights)
+
+			for _, field := range outcome.Fields {
+				p := probability(model[field])
+				i := bucketOf(p)
+				t.count[i]++
+				t.forecast[i] += p
+				t.observed[i] += outcome.Weights[field]
+			}
+		}
+	}
+
+	var standings []Standing
+	for name, t := range tallies {
+		standing := Standing{
+			Player:   name,
+			Spaces:   t.spaces,
+			Winnings: t.winnings,
+			Brier:    t.brier / float64(t.spaces),
+		}
+		for i := 0; i < buckets; i++ {
+			bucket := Bucket{
+				Lower: float64(i) / buckets,
+				Upper: float64(i+1) / buckets,
+				Count: t.count[i],
+			}
+			if t.count[i] > 0 {
+				bucket.Forecast = t.forecast[i] / float64(t.count[i])
+				bucket.Observed = t.observed[i] / float64(t.count[i])
+			}
+			standing.Reliability = append(standing.Reliability, bucket)
+		}
+		standings = append(standings, standing)
+	}
+
+	sort.Slice(standings, func(i, j int) bool {
+		if standings[i].Winnings != standings[j].Winnings {
+			return standings[i].Winnings > standings[j].Winnings
+		}
+		if standings[i].Brier != standings[j].Brier {
+			return standings[i].Brier < standings[j].Brier
+		}
+		return standings[i].Player < standings[j].Player
+	})
+
+	for i := range standings {
+		standings[i].Rank = i + 1
+	}
+
+	return standings
+}
+
+// mean squared error of a model's certainties against the resolution,
+// a field resolved with weight 0.5 counts as observed half the time
+func Brier(model map[string]float64, fields []string, weights map[string]float64) float64 {
+	if len(fields) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, field := range fields {
+		diff := probability(model[field]) - weights[field]
+		sum += diff * diff
+	}
+	return sum / float64(len(fields))
+}
+
+// certainties are stored as percentages
+func probability(certainty float64) float64 {
+	return math.Min(math.Max(certainty/100, 0), 1)
+}
+
+func bucketOf(p float64) int {
+	i := int(p * buckets)
+	if i >= buckets {
+		i = buckets - 1
+	}
+	return i
+}
+
+// Brier score assumed for players with no resolved history,
+// what a flat 50% forecast on every field would earn
+const unknownBrier = 0.25
+
+// weight each player's forecasts by calibration, 1 - mean Brier score,
+// so a perfectly calibrated player counts for 1 -> name: weight
+func Weights(standings []Standing, models map[string]map[string]float64) map[string]float64 {
+	brier := make(map[string]float64)
+	for _, standing := range standings {
+		brier[standing.Player] = standing.Brier
+	}
+
+	weights := make(map[string]float64)
+	for name := range models {
+		b, ok := brier[name]
+		if !ok {
+			b = unknownBrier
+		}
+		weights[name] = 1 - b
+	}
+	return weights
+}
@@ -0,0 +1,155 @@
+package score
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestBrier(t *testing.T) {
+	fields := []string{"yes", "no"}
+	cases := []struct {
+		name    string
+		model   map[string]float64
+		weights map[string]float64
+		want    float64
+	}{
+		{"certain and right", map[string]float64{"yes": 100, "no": 0}, map[string]float64{"yes": 1}, 0},
+		{"certain and wrong", map[string]float64{"yes": 0, "no": 100}, map[string]float64{"yes": 1}, 1},
+		{"flat", map[string]float64{"yes": 50, "no": 50}, map[string]float64{"yes": 1}, 0.25},
+		{"split resolution", map[string]float64{"yes": 50, "no": 50}, map[string]float64{"yes": 0.5, "no": 0.5}, 0},
+		{"out of range clamped", map[string]float64{"yes": 150, "no": -20}, map[string]float64{"yes": 1}, 0},
+	}
+
+	for _, c := range cases {
+		if got := Brier(c.model, fields, c.weights); !near(got, c.want) {
+			t.Errorf("%s: got %g, want %g", c.name, got, c.want)
+		}
+	}
+	if Brier(nil, nil, nil) != 0 {
+		t.Error("no fields did not score 0")
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	fields := []string{"a", "b"}
+	outcomes := []Outcome{
+		{
+			Space:   "s1",
+			Weights: map[string]float64{"a": 1},
+			Fields:  fields,
+			Models: map[string]map[string]float64{
+				"sharp": {"a": 90, "b": 10},
+				"flat":  {"a": 50, "b": 50},
+				"wrong": {"a": 10, "b": 90},
+			},
+			Winnings: map[string]float64{"sharp": 8, "flat": 0, "wrong": -8},
+		},
+		{
+			Space:   "s2",
+			Weights: map[string]float64{"b": 1},
+			Fields:  fields,
+			Models: map[string]map[string]float64{
+				"sharp": {"a": 20, "b": 80},
+				"flat":  {"a": 50, "b": 50},
+			},
+			Winnings: map[string]float64{"sharp": 3, "flat": -3},
+		},
+	}
+
+	standings := Leaderboard(outcomes)
+	order := []string{"sharp", "flat", "wrong"} // 11, -3 and -8 won
+	if len(standings) != len(order) {
+		t.Fatalf("got %d standings, want %d", len(standings), len(order))
+	}
+	for i, name := range order {
+		if standings[i].Player != name || standings[i].Rank != i+1 {
+			t.Errorf("rank %d is %s, want %s", standings[i].Rank, standings[i].Player, name)
+		}
+	}
+
+	sharp := standings[0]
+	if sharp.Spaces != 2 || !near(sharp.Winnings, 11) || !near(sharp.Brier, (0.01+0.04)/2) {
+		t.Errorf("sharp: %+v", sharp)
+	}
+
+	// sharp forecast 0.9 and 0.8 on what happened, 0.1 and 0.2 on what didn't
+	for _, bucket := range sharp.Reliability {
+		switch bucket.Lower {
+		case 0.1, 0.2:
+			if bucket.Count != 1 || bucket.Observed != 0 {
+				t.Errorf("bucket %g: %+v, want one forecast that never came true", bucket.Lower, bucket)
+			}
+		case 0.8, 0.9:
+			if bucket.Count != 1 || bucket.Observed != 1 {
+				t.Errorf("bucket %g: %+v, want one forecast that came true", bucket.Lower, bucket)
+			}
+		}
+	}
+}
+
+func TestLeaderboardTies(t *testing.T) {
+	outcome := Outcome{
+		Weights: map[string]float64{"a": 1},
+		Fields:  []string{"a"},
+		Models: map[string]map[string]float64{
+			"zed":   {"a": 100},
+			"amy":   {"a": 100},
+			"vague": {"a": 60},
+		},
+		Winnings: map[string]float64{},
+	}
+
+	standings := Leaderboard([]Outcome{outcome})
+	// all won nothing, better calibration first, then by name
+	for i, name := range []string{"amy", "zed", "vague"} {
+		if standings[i].Player != name {
+			t.Errorf("rank %d is %s, want %s", i+1, standings[i].Player, name)
+		}
+	}
+}
+
+// multi-select settles every field as its own yes/no book, so a player
+// ranks on what all of them paid, the no sides included
+func TestLeaderboardMultiSelect(t *testing.T) {
+	outcome := Outcome{
+		Weights: map[string]float64{"x": 1, "y": 0}, // x happened, y didn't
+		Fields:  []string{"x", "y"},
+		Models: map[string]map[string]float64{
+			"both":    {"x": 90, "y": 10}, // right on x, and on y's no side
+			"x only":  {"x": 90, "y": 90},
+			"neither": {"x": 10, "y": 90},
+		},
+		// escrow paid out on x:yes plus y:no
+		Winnings: map[string]float64{"both": 9, "x only": 1, "neither": -10},
+	}
+
+	standings := Leaderboard([]Outcome{outcome})
+	for i, name := range []string{"both", "x only", "neither"} {
+		if standings[i].Player != name {
+			t.Errorf("rank %d is %s, want %s", i+1, standings[i].Player, name)
+		}
+	}
+	if !near(standings[0].Winnings, 9) || !near(standings[0].Brier, 0.01) {
+		t.Errorf("both: %+v, want 9 won at a Brier of 0.01", standings[0])
+	}
+	if !near(standings[1].Brier, (0.01+0.81)/2) {
+		t.Errorf("x only: Brier %g, want y's 90%% counted as a miss", standings[1].Brier)
+	}
+}
+
+func TestWeights(t *testing.T) {
+	standings := []Standing{{Player: "known", Brier: 0.1}}
+	models := map[string]map[string]float64{"known": {}, "new": {}}
+
+	weights := Weights(standings, models)
+	if !near(weights["known"], 0.9) || !near(weights["new"], 1-unknownBrier) {
+		t.Errorf("got %v", weights)
+	}
+	if _, ok := weights["absent"]; ok {
+		t.Error("weighted a player without a model")
+	}
+}
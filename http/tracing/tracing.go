@@ -0,0 +1,114 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"goyave.dev/goyave/v4"
+)
+
+// exporters Setup accepts
+const (
+	None   = "none"   // spans are created for propagation but never exported
+	Stdout = "stdout" // pretty-printed JSON on stdout, for local debugging
+)
+
+// where Middleware leaves the request's span context in goyave.Request.Extra
+const extraKey = "tracing.context"
+
+var tracer = otel.Tracer("riverboat")
+
+// install the global tracer provider and the W3C trace context propagator,
+// the returned func flushes and stops the exporter
+func Setup(exporter string, sampleRatio float64) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var spans sdktrace.SpanExporter
+	switch exporter {
+	case None:
+	case Stdout:
+		out, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, err
+		}
+		spans = out
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporter)
+	}
+
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName("riverboat"))),
+	}
+	if spans != nil {
+		options = append(options, sdktrace.WithBatcher(spans))
+	}
+
+	provider := sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// start a child of whatever span ctx carries
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// the span context Middleware attached to r, or the plain request context
+func Context(r *goyave.Request) context.Context {
+	if ctx, ok := r.Extra[extraKey].(context.Context); ok {
+		return ctx
+	}
+	return r.Request().Context()
+}
+
+// one server span per request, continuing the caller's traceparent if sent
+func Middleware(next goyave.Handler) goyave.Handler {
+	return func(response *goyave.Response, r *goyave.Request) {
+		route := "unmatched"
+		if r.Route() != nil {
+			route = r.Route().GetFullURI()
+		}
+
+		parent := otel.GetTextMapPropagator().Extract(r.Request().Context(), propagation.HeaderCarrier(r.Header()))
+		ctx, span := tracer.Start(parent, r.Method()+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethod(r.Method()),
+				semconv.HTTPRoute(route),
+			),
+		)
+		defer span.End()
+
+		if r.Extra == nil {
+			r.Extra = make(map[string]interface{})
+		}
+		r.Extra[extraKey] = ctx
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(response.Header()))
+
+		next(response, r)
+
+		status := response.GetStatus()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPStatusCode(status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
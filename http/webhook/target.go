@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// a target that resolves to loopback, link-local, private or otherwise
+// internal addresses, which webhooks must never reach
+var ErrBlocked = errors.New("webhook target is not a public address")
+
+// ranges net.IP has no predicate for: carrier-grade NAT, which some clouds
+// use for internal services, and the benchmarking range
+var reserved = []*net.IPNet{
+	cidr("100.64.0.0/10"),
+	cidr("198.18.0.0/15"),
+}
+
+func cidr(s string) *net.IPNet {
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// whether ip is somewhere a webhook may not be sent, such as the cloud
+// metadata service at 169.254.169.254 or a 10.x internal service
+func blocked(ip net.IP) bool {
+	for _, network := range reserved {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return ip == nil ||
+		ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified()
+}
+
+// check a url on registration: http or https, and every address its host
+// resolves to is public
+func CheckTarget(ctx context.Context, target string) error {
+	u, err := url.ParseRequestURI(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("webhook url must be http or https")
+	}
+
+	if ip := net.ParseIP(u.Hostname()); ip != nil {
+		if blocked(ip) {
+			return ErrBlocked
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if blocked(addr.IP) {
+			return ErrBlocked
+		}
+	}
+	return nil
+}
+
+// client that checks the address it actually connects to, so a host that
+// resolves differently at send time, or redirects inward, is still refused
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if blocked(net.ParseIP(host)) {
+				return ErrBlocked
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // a proxy would be the address checked, not the target
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
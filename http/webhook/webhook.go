@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Riverboat-Signature"
+	EventHeader     = "X-Riverboat-Event"
+	DeliveryHeader  = "X-Riverboat-Delivery"
+
+	MaxAttempts = 8
+	baseDelay   = 30 * time.Second
+	maxDelay    = 6 * time.Hour
+)
+
+// a queued delivery joined with the webhook it targets
+type Job struct {
+	Delivery string
+	URL      string
+	Secret   string
+	Event    string
+	Payload  string
+	Attempts int
+}
+
+// random hex secret handed to the subscriber once, on registration
+func NewSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// "sha256=" followed by the hex HMAC-SHA256 of body keyed by secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// wait before the next attempt, doubling from baseDelay up to maxDelay
+func Backoff(attempts int) time.Duration {
+	delay := baseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	return delay
+}
+
+// POST the signed payload, any non-2xx status counts as a failure
+func Send(client *http.Client, job Job) (int, error) {
+	body := []byte(job.Payload)
+
+	req, err := http.NewRequest(http.MethodPost, job.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(job.Secret, body))
+	req.Header.Set(EventHeader, job.Event)
+	req.Header.Set(DeliveryHeader, job.Delivery)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("webhook responded %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
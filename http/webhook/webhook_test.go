@@ -0,0 +1,127 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	// HMAC-SHA256 test case 2 from RFC 4231
+	got := Sign("Jefe", []byte("what do ya want for nothing?"))
+	want := "sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		8:  64 * time.Minute,
+		10: 256 * time.Minute,
+		11: 6 * time.Hour,
+		50: 6 * time.Hour,
+	}
+	for attempts, want := range cases {
+		if got := Backoff(attempts); got != want {
+			t.Errorf("Backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	job := Job{
+		Delivery: "d1",
+		URL:      "",
+		Secret:   "s3cret",
+		Event:    "model.submitted",
+		Payload:  `{"event":"model.submitted"}`,
+	}
+
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer server.Close()
+	job.URL = server.URL
+
+	code, err := Send(server.Client(), job)
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("Send = %d, %v", code, err)
+	}
+
+	r, body := <-received, <-bodies
+	if r.Method != http.MethodPost || string(body) != job.Payload {
+		t.Errorf("got %s %q", r.Method, body)
+	}
+	if r.Header.Get(EventHeader) != job.Event || r.Header.Get(DeliveryHeader) != job.Delivery {
+		t.Errorf("event and delivery headers: %v", r.Header)
+	}
+	// a receiver verifies by signing the raw body with its secret
+	if !hmac.Equal([]byte(r.Header.Get(SignatureHeader)), []byte(Sign(job.Secret, body))) {
+		t.Errorf("signature %s does not match the body", r.Header.Get(SignatureHeader))
+	}
+}
+
+func TestSendFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	code, err := Send(server.Client(), Job{URL: server.URL, Payload: "{}"})
+	if err == nil || code != http.StatusServiceUnavailable {
+		t.Errorf("Send = %d, %v, want 503 and an error", code, err)
+	}
+}
+
+func TestCheckTarget(t *testing.T) {
+	cases := map[string]bool{
+		"https://93.184.216.34/hook":              true,
+		"http://127.0.0.1:8080/hook":              false,
+		"http://169.254.169.254/latest/meta-data": false,
+		"http://10.0.0.5/internal":                false,
+		"http://192.168.1.1/":                     false,
+		"http://[::1]/":                           false,
+		"http://0.0.0.0/":                         false,
+		"http://100.64.0.1/":                      false,
+		"http://100.127.255.254/":                 false,
+		"http://100.128.0.1/":                     true, // just past CGNAT
+		"http://198.18.0.1/":                      false,
+		"http://198.19.255.254/":                  false,
+		"http://198.20.0.1/":                      true,
+		"http://[::ffff:100.64.0.1]/":             false,
+		"ftp://93.184.216.34/":                    false,
+		"not a url":                               false,
+	}
+	for target, ok := range cases {
+		err := CheckTarget(context.Background(), target)
+		if (err == nil) != ok {
+			t.Errorf("CheckTarget(%s) = %v, want ok=%v", target, err, ok)
+		}
+	}
+}
+
+func TestNewClientRefusesLoopback(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	_, err := Send(NewClient(time.Second), Job{URL: server.URL, Payload: "{}"})
+	if !errors.Is(err, ErrBlocked) || called {
+		t.Errorf("got %v, want ErrBlocked before reaching the receiver", err)
+	}
+}
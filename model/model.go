@@ -1,17 +1,140 @@
 package model
 
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// kinds of outcome space, an empty kind is treated as Categorical
+const (
+	Categorical = "categorical" // mutually exclusive labels
+	Numeric     = "numeric"     // buckets generated from Min, Max and Step
+	Binary      = "binary"      // single probability that Fields[0] happens
+	MultiSelect = "multi"       // each field is an independent yes/no
+)
+
+// key for the "no" side of a binary or multi-select field in payouts
+const NoSuffix = ":no"
+
 type Space struct {
-	Fields      []string `json:"fields"`
-	Name        string   `json:"name"`
-	Pattern     string   `json:"pattern"`
-	Stake       float64  `json:"stake"`
-	Uuid        string   `json:"uuid"`
-	Description string   `json:"description"`
+	Kind        string             `json:"kind"`
+	Fields      []string           `json:"fields"`
+	Name        string             `json:"name"`
+	Pattern     string             `json:"pattern"`
+	Stake       float64            `json:"stake"`
+	Uuid        string             `json:"uuid"`
+	Description string             `json:"description"`
+	Resolution  string             `json:"resolution,omitempty"` // field that came true
+	Weights     map[string]float64 `json:"weights,omitempty"`    // share of the resolution per field
+	Resolved    int64              `json:"resolved,omitempty"`   // ms since epoch
+	Min         float64            `json:"min,omitempty"`
+	Max         float64            `json:"max,omitempty"`
+	Step        float64            `json:"step,omitempty"`
+	Parent      string             `json:"parent,omitempty"`    // space this one is conditional on
+	Condition   string             `json:"condition,omitempty"` // parent field that must come true
+	Void        bool               `json:"void,omitempty"`      // cancelled or condition failed, stakes refunded
+	Children    []Space            `json:"children,omitempty"`
+}
+
+// labels for each [lower, upper) bucket of a numeric range; bounds are
+// rounded to the places min, max and step are given in, so 0.1 steps read
+// 0.3 rather than 0.30000000000000004
+func BucketFields(min float64, max float64, step float64) []string {
+	scale := math.Pow(10, float64(places(min, max, step)))
+	round := func(x float64) float64 { return math.Round(x*scale) / scale }
+
+	var fields []string
+	for i := 0; round(min+float64(i)*step) < max; i++ {
+		lower := round(min + float64(i)*step)
+		upper := math.Min(round(min+float64(i+1)*step), max)
+		fields = append(fields, formatBound(lower)+".."+formatBound(upper))
+	}
+	return fields
+}
+
+// the most decimal places any of xs is written with
+func places(xs ...float64) int {
+	most := 0
+	for _, x := range xs {
+		if _, frac, ok := strings.Cut(formatBound(x), "."); ok && len(frac) > most {
+			most = len(frac)
+		}
+	}
+	return most
+}
+
+func formatBound(x float64) string {
+	return strconv.FormatFloat(x, 'f', -1, 64)
+}
+
+// one timestamped submission in a player's history for a space;
+// Created and Retired are milliseconds since epoch
+type ModelRevision struct {
+	Uuid    string             `json:"uuid"`
+	Model   map[string]float64 `json:"model"`
+	Created int64              `json:"created"`
+	Retired int64              `json:"retired,omitempty"`
+	Current bool               `json:"current"`
+	Version int64              `json:"version"` // counts a player's submissions to the space
+}
+
+// current models of a space read in one transaction, with the revision
+// behind each and the space's models version at the time
+type Snapshot struct {
+	Models    map[string]map[string]float64
+	Revisions map[string]string // name -> revision uuid
+	Versions  map[string]int64  // name -> model version
+	Version   int64
+}
+
+// one page of a list endpoint; NextCursor is empty on the last page
+type Page struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor"`
+}
+
+// a page of a space's stored payouts, stale once the models have changed
+// since the version the payouts were computed from
+type PayoutsPage struct {
+	Page
+	ModelsVersion  int64 `json:"models_version"`
+	PayoutsVersion int64 `json:"payouts_version"`
+	Stale          bool  `json:"stale"`
+}
+
+// a player's current model in a space -> { field: certainty, ... }
+type PlayerModel struct {
+	Name  string             `json:"name"`
+	Uuid  string             `json:"uuid"`
+	Model map[string]float64 `json:"model"`
+}
+
+// a player's stored payout in a space -> { field: payout, ... }, with the
+// version of the model it was computed from
+type PlayerPayout struct {
+	Name    string             `json:"name"`
+	Uuid    string             `json:"uuid"`
+	Payout  map[string]float64 `json:"payout"`
+	Version int64              `json:"version,omitempty"`
+}
+
+// which page of a list to read and how; Limit 0 reads every row
+type ListQuery struct {
+	Cursor string
+	Limit  int
+	Sort   string // field to sort by, prefixed with - for descending
+	Owner  string // circles spawned by
+	State  string // spaces that are open, resolved or void
+	Prefix string // players whose name starts with
 }
 
 type Circle struct {
-	Name string `json:"name"`
-	Uuid string `json:"uuid"`
+	Name    string `json:"name"`
+	Uuid    string `json:"uuid"`
+	Owner   string `json:"owner,omitempty"`   // spawned_by
+	Created int64  `json:"created,omitempty"` // ms since epoch
 	// all_joined	false
 	// all_modeled	false
 	// all_paid	false
@@ -19,9 +142,84 @@ type Circle struct {
 	// uuid	1251094a-b643-4ccb-b12e-081c38ddb700
 }
 
+// subscription to a circle's events; Secret is only returned on creation
+type Webhook struct {
+	Uuid    string   `json:"uuid"`
+	Url     string   `json:"url"`
+	Events  []string `json:"events"`
+	Secret  string   `json:"secret,omitempty"`
+	Created int64    `json:"created"`
+}
+
+// one queued or attempted webhook delivery; Status is pending, delivered or dead
+type Delivery struct {
+	Uuid        string `json:"uuid"`
+	Event       string `json:"event"`
+	Status      string `json:"status"`
+	Attempts    int64  `json:"attempts"`
+	LastStatus  int64  `json:"last_status"`
+	LastError   string `json:"last_error"`
+	Created     int64  `json:"created"`
+	NextAttempt int64  `json:"next_attempt"`
+	Delivered   int64  `json:"delivered,omitempty"`
+}
+
+// a player whose models are submitted by a scripted strategy;
+// Target is the name of the player a copy bot follows
+type Bot struct {
+	Name     string `json:"name"`
+	Uuid     string `json:"uuid"`
+	Strategy string `json:"strategy"`
+	Target   string `json:"target,omitempty"`
+}
+
 type Player struct {
 	Name  string  `json:"name"`
 	Uuid  string  `json:"uuid"`
 	Money float64 `json:"money"`
 	Risk  int64   `json:"risk"`
 }
+
+// every node and relationship in the graph, as written by riverboat export
+type Dump struct {
+	Nodes         []DumpNode         `json:"nodes"`
+	Relationships []DumpRelationship `json:"relationships"`
+}
+
+type DumpNode struct {
+	ID     string          `json:"id"`
+	Labels []string        `json:"labels"`
+	Props  map[string]Prop `json:"props"`
+}
+
+type DumpRelationship struct {
+	Start string          `json:"start"`
+	End   string          `json:"end"`
+	Type  string          `json:"type"`
+	Props map[string]Prop `json:"props"`
+}
+
+// property value tagged with its Neo4j type: int, float, string, bool or list:<type>
+type Prop struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// served by /readyz, with one check per dependency
+type Readiness struct {
+	Ready   bool             `json:"ready"`
+	Checks  map[string]Check `json:"checks"`
+	Checked time.Time        `json:"checked"`
+}
+
+type Check struct {
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// graph-wide counts exported as business gauges
+type Totals struct {
+	OpenSpaces    int64   `json:"open_spaces"`
+	ActivePlayers int64   `json:"active_players"`
+	Escrow        float64 `json:"escrow"`
+}
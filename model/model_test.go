@@ -0,0 +1,40 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBucketFields(t *testing.T) {
+	cases := []struct {
+		name           string
+		min, max, step float64
+		want           []string
+	}{
+		{"whole steps", 0, 30, 10, []string{"0..10", "10..20", "20..30"}},
+		{"tenths", 0, 0.5, 0.1, []string{"0..0.1", "0.1..0.2", "0.2..0.3", "0.3..0.4", "0.4..0.5"}},
+		{"last bucket cut at max", 0, 25, 10, []string{"0..10", "10..20", "20..25"}},
+		{"offset min", 0.05, 0.35, 0.1, []string{"0.05..0.15", "0.15..0.25", "0.25..0.35"}},
+		{"negative", -1, 1, 0.5, []string{"-1..-0.5", "-0.5..0", "0..0.5", "0.5..1"}},
+		{"large", 1e6, 3e6, 1e6, []string{"1000000..2000000", "2000000..3000000"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := BucketFields(c.min, c.max, c.step); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("BucketFields(%g, %g, %g) = %v, want %v", c.min, c.max, c.step, got, c.want)
+			}
+		})
+	}
+}
+
+func TestBucketFieldsManySteps(t *testing.T) {
+	// a sum of 0.01s drifts well before 1000 of them
+	fields := BucketFields(0, 10, 0.01)
+	if len(fields) != 1000 {
+		t.Fatalf("got %d buckets, want 1000", len(fields))
+	}
+	if fields[999] != "9.99..10" {
+		t.Errorf("last bucket is %q, want 9.99..10", fields[999])
+	}
+}
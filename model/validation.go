@@ -1,15 +1,30 @@
 package model
 
 import (
+	"errors"
+	"math"
+
 	"goyave.dev/goyave/v4/validation"
 )
 
+// most buckets a numeric space may generate
+const maxBuckets = 200
+
 // SubmitModel()
 var (
 	SubmissionProps = validation.RuleSet{
 		"puuid": validation.List{"required", "string"},
 		"suuid": validation.List{"required", "string"},
 		"model": validation.List{"required", "object"},
+		// the version the client last saw, like If-Match
+		"expected_version": validation.List{"integer", "min:0"},
+	}
+)
+
+var (
+	SimulationProps = validation.RuleSet{
+		"puuid": validation.List{"required", "string"},
+		"model": validation.List{"required", "object"},
 	}
 )
 
@@ -33,11 +48,270 @@ var (
 	}
 )
 
+var (
+	RandomProps = validation.RuleSet{
+		"cuuid":    validation.List{"required", "string"},
+		"strategy": validation.List{"string", "in:uniform,noisy-consensus,contrarian,copy"},
+		"target":   validation.List{"string"},
+	}
+)
+
 var (
 	SpaceProps = validation.RuleSet{
 		"uuid":    validation.List{"required", "string"},
-		"fields":  validation.List{"required", "array:string"},
-		"pattern": validation.List{"required", "string"},
-		"stake":   validation.List{"required", "numeric"},
+		"fields":  validation.List{"array:string"},
+		"pattern": validation.List{"string"},
+		"stake":   validation.List{"numeric"},
+		"dry_run": validation.List{"bool"},
+	}
+)
+
+var (
+	AsOfProps = validation.RuleSet{
+		"as_of": validation.List{"required", "integer", "min:0"},
 	}
 )
+
+var (
+	WebhookProps = validation.RuleSet{
+		"cuuid":  validation.List{"required", "string"},
+		"url":    validation.List{"required", "string"},
+		"events": validation.List{"array:string"},
+	}
+)
+
+var (
+	UuidProps = validation.RuleSet{
+		"uuid": validation.List{"required", "string"},
+	}
+)
+
+var (
+	WindowProps = validation.RuleSet{
+		"from": validation.List{"integer", "min:0"},
+		"to":   validation.List{"integer", "min:0"},
+	}
+)
+
+// list endpoints take a cursor from the previous page's next_cursor
+var (
+	CircleListProps = validation.RuleSet{
+		"cursor": validation.List{"string"},
+		"limit":  validation.List{"integer", "min:1", "max:200"},
+		"sort":   validation.List{"string", "in:name,-name,created,-created"},
+		"owner":  validation.List{"string"},
+	}
+)
+
+var (
+	SpaceListProps = validation.RuleSet{
+		"cursor": validation.List{"string"},
+		"limit":  validation.List{"integer", "min:1", "max:200"},
+		"sort":   validation.List{"string", "in:created,-created,name,-name"},
+		"state":  validation.List{"string", "in:open,resolved,void"},
+	}
+)
+
+var (
+	PlayerListProps = validation.RuleSet{
+		"cursor": validation.List{"string"},
+		"limit":  validation.List{"integer", "min:1", "max:200"},
+		"sort":   validation.List{"string", "in:name,-name,money,-money"},
+		"prefix": validation.List{"string"},
+	}
+)
+
+// models and payouts are keyed by player name
+var (
+	NameListProps = validation.RuleSet{
+		"cursor": validation.List{"string"},
+		"limit":  validation.List{"integer", "min:1", "max:200"},
+		"sort":   validation.List{"string", "in:name,-name"},
+		"prefix": validation.List{"string"},
+	}
+)
+
+var (
+	ConsensusProps = validation.RuleSet{
+		"method": validation.List{"string", "in:mean,median,trimmed,logodds"},
+		"trim":   validation.List{"numeric", "min:0", "max:0.45"},
+	}
+)
+
+var (
+	NewSpaceProps = validation.RuleSet{
+		"cuuid":       validation.List{"required", "string"},
+		"name":        validation.List{"required", "string"},
+		"description": validation.List{"string"},
+		"kind":        validation.List{"required", "string", "in:categorical,numeric,binary,multi"},
+		"fields":      validation.List{"array:string"},
+		"pattern":     validation.List{"required", "string", "in:waterfall,parimutuel"}, // calc.Rules
+		"stake":       validation.List{"required", "numeric", "min:0"},
+		"min":         validation.List{"numeric"},
+		"max":         validation.List{"numeric"},
+		"step":        validation.List{"numeric"},
+		"parent":      validation.List{"string"},
+		"condition":   validation.List{"string"},
+	}
+)
+
+var (
+	ResolveProps = validation.RuleSet{
+		"suuid":   validation.List{"required", "string"},
+		"field":   validation.List{"string"},
+		"weights": validation.List{"object"},
+		"void":    validation.List{"bool"},
+	}
+)
+
+// check the fields a space needs for its kind, generating numeric buckets
+func CheckSpace(space *Space) error {
+	switch space.Kind {
+	case "", Categorical, MultiSelect:
+		if len(space.Fields) < 2 {
+			return errors.New("space needs at least two fields")
+		}
+	case Numeric:
+		if space.Step <= 0 || space.Max <= space.Min {
+			return errors.New("numeric space needs min < max and a positive step")
+		}
+		if (space.Max-space.Min)/space.Step > maxBuckets {
+			return errors.New("numeric space has too many buckets")
+		}
+		space.Fields = BucketFields(space.Min, space.Max, space.Step)
+	case Binary:
+		if len(space.Fields) == 0 {
+			space.Fields = []string{"yes"}
+		}
+		if len(space.Fields) != 1 {
+			return errors.New("binary space has exactly one field")
+		}
+	default:
+		return errors.New("unknown space kind: " + space.Kind)
+	}
+	if (space.Parent == "") != (space.Condition == "") {
+		return errors.New("conditional space needs both parent and condition")
+	}
+	return checkDistinct(space.Fields)
+}
+
+// every field a space can resolve to
+func Outcomes(space Space) []string {
+	if space.Kind != Binary && space.Kind != MultiSelect {
+		return space.Fields
+	}
+	var outcomes []string
+	for _, field := range space.Fields {
+		outcomes = append(outcomes, field, field+NoSuffix)
+	}
+	return outcomes
+}
+
+// check a space can be resolved now to weights -> field: share, summing to 1,
+// or for multi-select -> field: 1 if it happened, 0 if not
+func CheckResolution(space Space, parent Space, weights map[string]float64) error {
+	if space.Resolved != 0 {
+		return errors.New("space is already resolved")
+	}
+	if space.Parent != "" && (parent.Weights[space.Condition] != 1 || parent.Void) {
+		return errors.New("parent space has not resolved to the condition")
+	}
+	if space.Kind == MultiSelect {
+		return checkIndependent(space, weights)
+	}
+
+	known := make(map[string]bool)
+	for _, outcome := range Outcomes(space) {
+		known[outcome] = true
+	}
+
+	total := 0.0
+	for field, weight := range weights {
+		if !known[field] {
+			return errors.New("unknown field: " + field)
+		}
+		if weight < 0 || math.IsNaN(weight) {
+			return errors.New("weights must not be negative")
+		}
+		total += weight
+	}
+	if math.Abs(total-1) > 1e-9 {
+		return errors.New("weights must sum to 1")
+	}
+	return nil
+}
+
+// every multi-select field settles its own yes/no book, so each needs a
+// weight of exactly 0 or 1
+func checkIndependent(space Space, weights map[string]float64) error {
+	known := make(map[string]bool)
+	for _, field := range space.Fields {
+		known[field] = true
+		weight, ok := weights[field]
+		if !ok {
+			return errors.New("missing weight for field: " + field)
+		}
+		if weight != 0 && weight != 1 {
+			return errors.New("multi-select weights must be 0 or 1")
+		}
+	}
+	for field := range weights {
+		if !known[field] {
+			return errors.New("unknown field: " + field)
+		}
+	}
+	return nil
+}
+
+// outcome: share of each payout a resolution settles on; a multi-select
+// field settles its book on the field when it happened, field+NoSuffix if not
+func Settlement(space Space, weights map[string]float64) map[string]float64 {
+	if space.Kind != MultiSelect {
+		return weights
+	}
+	settled := make(map[string]float64)
+	for _, field := range space.Fields {
+		if weights[field] == 1 {
+			settled[field] = 1
+		} else {
+			settled[field+NoSuffix] = 1
+		}
+	}
+	return settled
+}
+
+// check a submitted model against the space it is for -> field: certainty
+func CheckModel(space Space, spread map[string]float64) error {
+	known := make(map[string]bool)
+	for _, field := range space.Fields {
+		known[field] = true
+	}
+
+	total := 0.0
+	for field, cert := range spread {
+		if !known[field] {
+			return errors.New("unknown field: " + field)
+		}
+		if cert < 0 || cert > 100 || math.IsNaN(cert) {
+			return errors.New("certainty must be between 0 and 100")
+		}
+		total += cert
+	}
+
+	// mutually exclusive fields share one distribution
+	if (space.Kind == "" || space.Kind == Categorical || space.Kind == Numeric) && total > 100.0001 {
+		return errors.New("certainties must not sum past 100")
+	}
+	return nil
+}
+
+func checkDistinct(fields []string) error {
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		if field == "" || seen[field] {
+			return errors.New("fields must be distinct and non-empty")
+		}
+		seen[field] = true
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckResolution(t *testing.T) {
+	categorical := Space{Kind: Categorical, Fields: []string{"a", "b"}}
+	binary := Space{Kind: Binary, Fields: []string{"yes"}}
+	multi := Space{Kind: MultiSelect, Fields: []string{"a", "b"}}
+
+	cases := []struct {
+		name    string
+		space   Space
+		weights map[string]float64
+		ok      bool
+	}{
+		{"categorical single field", categorical, map[string]float64{"a": 1}, true},
+		{"categorical split", categorical, map[string]float64{"a": 0.25, "b": 0.75}, true},
+		{"categorical short of 1", categorical, map[string]float64{"a": 0.5}, false},
+		{"categorical unknown field", categorical, map[string]float64{"c": 1}, false},
+		{"categorical negative", categorical, map[string]float64{"a": -1, "b": 2}, false},
+		{"binary no", binary, map[string]float64{"yes" + NoSuffix: 1}, true},
+		{"multi every field", multi, map[string]float64{"a": 1, "b": 0}, true},
+		{"multi all happened", multi, map[string]float64{"a": 1, "b": 1}, true},
+		{"multi missing field", multi, map[string]float64{"a": 1}, false},
+		{"multi fractional", multi, map[string]float64{"a": 0.5, "b": 0.5}, false},
+		{"multi no outcome", multi, map[string]float64{"a": 1, "b": 0, "a" + NoSuffix: 0}, false},
+		{"already resolved", Space{Kind: Categorical, Fields: []string{"a", "b"}, Resolved: 1}, map[string]float64{"a": 1}, false},
+	}
+
+	for _, c := range cases {
+		err := CheckResolution(c.space, Space{}, c.weights)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: got %v, want ok=%v", c.name, err, c.ok)
+		}
+	}
+}
+
+func TestCheckResolutionCondition(t *testing.T) {
+	child := Space{Kind: Categorical, Fields: []string{"a", "b"}, Parent: "p", Condition: "yes"}
+	weights := map[string]float64{"a": 1}
+
+	if err := CheckResolution(child, Space{Weights: map[string]float64{"yes": 1}}, weights); err != nil {
+		t.Errorf("parent resolved to the condition: %v", err)
+	}
+	if err := CheckResolution(child, Space{Weights: map[string]float64{"no": 1}}, weights); err == nil {
+		t.Error("parent resolved elsewhere: want an error")
+	}
+	if err := CheckResolution(child, Space{Weights: map[string]float64{"yes": 1}, Void: true}, weights); err == nil {
+		t.Error("parent void: want an error")
+	}
+}
+
+func TestSettlement(t *testing.T) {
+	multi := Space{Kind: MultiSelect, Fields: []string{"a", "b"}}
+	got := Settlement(multi, map[string]float64{"a": 1, "b": 0})
+	want := map[string]float64{"a": 1, "b" + NoSuffix: 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("multi: got %v, want %v", got, want)
+	}
+
+	weights := map[string]float64{"a": 0.5, "b": 0.5}
+	if got := Settlement(Space{Kind: Categorical}, weights); !reflect.DeepEqual(got, weights) {
+		t.Errorf("categorical: got %v, want the weights unchanged", got)
+	}
+}
@@ -0,0 +1,284 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"riverboat/http/calc"
+	"sort"
+)
+
+// ways a player can misreport their belief, checked against honesty
+const (
+	Extremize = "extremize" // everything on the most likely field
+	Hedge     = "hedge"     // halfway between belief and uniform
+	Flat      = "flat"      // uniform regardless of belief
+)
+
+var misreports = []string{Extremize, Hedge, Flat}
+
+// advantages closer to 0 than this are float noise, counted as ties
+const tie = 1e-9
+
+// skill tiers players are grouped into for reporting
+var tiers = []string{"low", "mid", "high"}
+
+type Config struct {
+	Spaces  int
+	Players int
+	Fields  int
+	Stake   float64
+	Seed    int64
+}
+
+// profit of one skill tier under one rule, over every player-space
+type Tier struct {
+	Rule     string  `json:"rule"`
+	Tier     string  `json:"tier"`
+	Samples  int     `json:"samples"`
+	Mean     float64 `json:"mean_profit"`
+	Variance float64 `json:"variance"`
+}
+
+// how honest reporting fared against one misreport under one rule;
+// Advantage is honest minus misreport expected profit, WinRate counts
+// trials where honesty did strictly better and TieRate those it matched
+type Honesty struct {
+	Rule      string  `json:"rule"`
+	Misreport string  `json:"misreport"`
+	Trials    int     `json:"trials"`
+	Advantage float64 `json:"mean_advantage"`
+	WinRate   float64 `json:"honest_win_rate"`
+	TieRate   float64 `json:"tie_rate"`
+}
+
+type Report struct {
+	Tiers   []Tier    `json:"tiers"`
+	Honesty []Honesty `json:"honesty"`
+}
+
+type player struct {
+	name  string
+	skill float64 // 0 reports noise, 1 knows the truth
+	bias  float64 // > 0 overconfident, < 0 underconfident
+}
+
+// run cfg.Spaces synthetic spaces through every rule
+func Run(cfg Config, rules map[string]calc.Rule) (Report, error) {
+	rng := rand.New(rand.NewSource(cfg.Seed))
+
+	fields := make([]string, cfg.Fields)
+	for i := range fields {
+		fields[i] = fmt.Sprintf("f%d", i)
+	}
+
+	players := make([]player, cfg.Players)
+	for i := range players {
+		players[i] = player{
+			name:  fmt.Sprintf("p%d", i),
+			skill: rng.Float64(),
+			bias:  rng.Float64() - 0.5,
+		}
+	}
+
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	profits := make(map[string]map[string][]float64) // rule: tier: profits
+	advantages := make(map[string]map[string][]float64)
+	for _, name := range names {
+		profits[name] = make(map[string][]float64)
+		advantages[name] = make(map[string][]float64)
+	}
+
+	for s := 0; s < cfg.Spaces; s++ {
+		truth := dirichlet(rng, len(fields))
+		outcome := draw(rng, truth)
+
+		beliefs := make(map[string][]float64)
+		models := make(map[string]map[string]float64)
+		for _, p := range players {
+			beliefs[p.name] = believe(rng, p, truth)
+			models[p.name] = report(fields, beliefs[p.name])
+		}
+
+		// one player per space tries every misreport against honesty
+		tester := players[s%len(players)]
+
+		for _, name := range names {
+			rule := rules[name]
+			payouts, err := rule(models, fields, cfg.Stake)
+			if err != nil {
+				return Report{}, err
+			}
+
+			for _, p := range players {
+				tier := tierOf(p.skill)
+				profits[name][tier] = append(profits[name][tier], payouts[p.name][fields[outcome]])
+			}
+
+			honest := expected(payouts[tester.name], fields, truth)
+			for _, misreport := range misreports {
+				swapped := make(map[string]map[string]float64)
+				for n, m := range models {
+					swapped[n] = m
+				}
+				swapped[tester.name] = report(fields, misreported(misreport, beliefs[tester.name]))
+
+				strategic, err := rule(swapped, fields, cfg.Stake)
+				if err != nil {
+					return Report{}, err
+				}
+				advantage := honest - expected(strategic[tester.name], fields, truth)
+				advantages[name][misreport] = append(advantages[name][misreport], advantage)
+			}
+		}
+	}
+
+	var result Report
+	for _, name := range names {
+		for _, tier := range tiers {
+			samples := profits[name][tier]
+			mean, variance := moments(samples)
+			result.Tiers = append(result.Tiers, Tier{
+				Rule:     name,
+				Tier:     tier,
+				Samples:  len(samples),
+				Mean:     mean,
+				Variance: variance,
+			})
+		}
+		for _, misreport := range misreports {
+			trials := advantages[name][misreport]
+			mean, _ := moments(trials)
+			wins, ties := 0, 0
+			for _, advantage := range trials {
+				switch {
+				case math.Abs(advantage) < tie:
+					ties++
+				case advantage > 0:
+					wins++
+				}
+			}
+			honesty := Honesty{Rule: name, Misreport: misreport, Trials: len(trials), Advantage: mean}
+			if len(trials) > 0 {
+				honesty.WinRate = float64(wins) / float64(len(trials))
+				honesty.TieRate = float64(ties) / float64(len(trials))
+			}
+			result.Honesty = append(result.Honesty, honesty)
+		}
+	}
+
+	return result, nil
+}
+
+// random distribution over n fields, flat Dirichlet via normalized exponentials
+func dirichlet(rng *rand.Rand, n int) []float64 {
+	dist := make([]float64, n)
+	for i := range dist {
+		dist[i] = rng.ExpFloat64()
+	}
+	return scale(dist)
+}
+
+func draw(rng *rand.Rand, dist []float64) int {
+	x := rng.Float64()
+	for i, p := range dist {
+		x -= p
+		if x < 0 {
+			return i
+		}
+	}
+	return len(dist) - 1
+}
+
+// mix the truth with noise by skill, then sharpen or flatten by bias
+func believe(rng *rand.Rand, p player, truth []float64) []float64 {
+	noise := dirichlet(rng, len(truth))
+	belief := make([]float64, len(truth))
+	for i := range truth {
+		mixed := p.skill*truth[i] + (1-p.skill)*noise[i]
+		belief[i] = math.Pow(mixed, 1+p.bias)
+	}
+	return scale(belief)
+}
+
+func misreported(misreport string, belief []float64) []float64 {
+	bent := make([]float64, len(belief))
+	switch misreport {
+	case Extremize:
+		best := 0
+		for i := range belief {
+			if belief[i] > belief[best] {
+				best = i
+			}
+		}
+		bent[best] = 1
+	case Hedge:
+		for i := range belief {
+			bent[i] = belief[i]/2 + 0.5/float64(len(belief))
+		}
+	default:
+		for i := range belief {
+			bent[i] = 1 / float64(len(belief))
+		}
+	}
+	return bent
+}
+
+// a distribution as a model of percentage certainties
+func report(fields []string, dist []float64) map[string]float64 {
+	model := make(map[string]float64)
+	for i, field := range fields {
+		model[field] = dist[i] * 100
+	}
+	return model
+}
+
+// payout a player expects if the truth is known -> outcome: payout
+func expected(payouts map[string]float64, fields []string, truth []float64) float64 {
+	sum := 0.0
+	for i, field := range fields {
+		sum += truth[i] * payouts[field]
+	}
+	return sum
+}
+
+func scale(values []float64) []float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	for i := range values {
+		values[i] /= total
+	}
+	return values
+}
+
+func tierOf(skill float64) string {
+	i := int(skill * float64(len(tiers)))
+	if i >= len(tiers) {
+		i = len(tiers) - 1
+	}
+	return tiers[i]
+}
+
+func moments(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, variance / float64(len(values))
+}
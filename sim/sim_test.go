@@ -0,0 +1,69 @@
+package sim
+
+import (
+	"reflect"
+	"riverboat/http/calc"
+	"testing"
+)
+
+var cfg = Config{Spaces: 50, Players: 6, Fields: 3, Stake: 10, Seed: 42}
+
+func TestRunIsDeterministic(t *testing.T) {
+	first, err := Run(cfg, calc.Rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := Run(cfg, calc.Rules)
+	if !reflect.DeepEqual(first, again) {
+		t.Error("two runs with one seed reported differently")
+	}
+
+	other := cfg
+	other.Seed = 43
+	if different, _ := Run(other, calc.Rules); reflect.DeepEqual(first, different) {
+		t.Error("another seed reported exactly the same")
+	}
+}
+
+func TestRunReportsEveryRule(t *testing.T) {
+	report, _ := Run(cfg, calc.Rules)
+
+	if want := len(calc.Rules) * len(tiers); len(report.Tiers) != want {
+		t.Errorf("got %d tier rows, want %d", len(report.Tiers), want)
+	}
+	if want := len(calc.Rules) * len(misreports); len(report.Honesty) != want {
+		t.Errorf("got %d honesty rows, want %d", len(report.Honesty), want)
+	}
+
+	samples := make(map[string]int)
+	for _, tier := range report.Tiers {
+		samples[tier.Rule] += tier.Samples
+	}
+	for rule := range calc.Rules {
+		if samples[rule] != cfg.Spaces*cfg.Players {
+			t.Errorf("%s: %d samples, want one per player-space", rule, samples[rule])
+		}
+	}
+	for _, honesty := range report.Honesty {
+		if honesty.Trials != cfg.Spaces {
+			t.Errorf("%s/%s: %d trials, want %d", honesty.Rule, honesty.Misreport, honesty.Trials, cfg.Spaces)
+		}
+	}
+}
+
+// a rule that pays nobody leaves honesty nothing to win, every trial ties
+func TestTiesAreNotWins(t *testing.T) {
+	nothing := func(models map[string]map[string]float64, fields []string, stake float64) (map[string]map[string]float64, error) {
+		return map[string]map[string]float64{}, nil
+	}
+
+	report, err := Run(cfg, map[string]calc.Rule{"nothing": nothing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, honesty := range report.Honesty {
+		if honesty.WinRate != 0 || honesty.TieRate != 1 {
+			t.Errorf("%s: win rate %g, tie rate %g, want 0 and 1", honesty.Misreport, honesty.WinRate, honesty.TieRate)
+		}
+	}
+}
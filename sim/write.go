@@ -0,0 +1,40 @@
+package sim
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+func WriteJSON(w io.Writer, report Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// one table for both sections, tier rows leave the rates empty
+func WriteCSV(w io.Writer, report Report) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"rule", "metric", "group", "samples", "mean", "variance", "honest_win_rate", "tie_rate"})
+
+	for _, tier := range report.Tiers {
+		out.Write([]string{
+			tier.Rule, "profit", tier.Tier, strconv.Itoa(tier.Samples),
+			format(tier.Mean), format(tier.Variance), "", "",
+		})
+	}
+	for _, honesty := range report.Honesty {
+		out.Write([]string{
+			honesty.Rule, "honesty", honesty.Misreport, strconv.Itoa(honesty.Trials),
+			format(honesty.Advantage), "", format(honesty.WinRate), format(honesty.TieRate),
+		})
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+func format(value float64) string {
+	return strconv.FormatFloat(value, 'f', 4, 64)
+}